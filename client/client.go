@@ -0,0 +1,279 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ytbs/indexer"
+	"ytbs/sync"
+	"ytbs/tracker"
+)
+
+// apiPrefix - path prefix of the JSON API
+const apiPrefix = "/api/v1"
+
+// Client - client for the ytbs server JSON API
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewClient - creates a new API client for the server at baseURL (e.g. http://localhost:8080).
+// If httpClient is nil, a client with a 30s timeout is used
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Timeout: 30 * time.Second,
+		}
+	}
+
+	return &Client{
+		httpClient: httpClient,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// SearchOptions - pagination and facet options of a search request
+type SearchOptions struct {
+	Offset int
+	Limit  int
+	Facets []string
+}
+
+// Search - searches issues, returning one page of results
+func (c *Client) Search(ctx context.Context, query string, filters indexer.SearchFilters, opts SearchOptions) (*indexer.SearchPage, error) {
+	params := filters.Query()
+	if query != "" {
+		params.Set("q", query)
+	}
+	if opts.Offset > 0 {
+		params.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	for _, f := range opts.Facets {
+		params.Add("facets", f)
+	}
+
+	var page indexer.SearchPage
+	if err := c.do(ctx, http.MethodGet, "/search", params, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Facets - returns value counts of the fields for issues matching the query and filters
+func (c *Client) Facets(ctx context.Context, query string, filters indexer.SearchFilters, fields ...string) (map[string][]indexer.FacetValue, error) {
+	page, err := c.Search(ctx, query, filters, SearchOptions{Limit: 1, Facets: fields})
+	if err != nil {
+		return nil, err
+	}
+	return page.Facets, nil
+}
+
+// Issue - returns the indexed issue by key; errors.Is(err, ErrNotFound) if it is not indexed
+func (c *Client) Issue(ctx context.Context, key string) (*tracker.IndexedIssue, error) {
+	var issue tracker.IndexedIssue
+	if err := c.do(ctx, http.MethodGet, "/issues/"+url.PathEscape(key), nil, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// IssueGraph - returns issues within depth links from the issue and links between them
+func (c *Client) IssueGraph(ctx context.Context, key string, depth int) (*indexer.LinkGraph, error) {
+	params := url.Values{}
+	if depth > 0 {
+		params.Set("depth", strconv.Itoa(depth))
+	}
+
+	var graph indexer.LinkGraph
+	if err := c.do(ctx, http.MethodGet, "/issues/"+url.PathEscape(key)+"/graph", params, &graph); err != nil {
+		return nil, err
+	}
+	return &graph, nil
+}
+
+// Rollup - returns descendant counts per status of an epic or parent issue
+func (c *Client) Rollup(ctx context.Context, key string) (*indexer.Rollup, error) {
+	var rollup indexer.Rollup
+	if err := c.do(ctx, http.MethodGet, "/issues/"+url.PathEscape(key)+"/rollup", nil, &rollup); err != nil {
+		return nil, err
+	}
+	return &rollup, nil
+}
+
+// WorklogReport - returns hours logged on issues matching the query and filters
+func (c *Client) WorklogReport(ctx context.Context, query string, filters indexer.SearchFilters, opts indexer.WorklogOptions) (*indexer.WorklogReport, error) {
+	params := filters.Query()
+	if query != "" {
+		params.Set("q", query)
+	}
+	for name, values := range opts.Query() {
+		params[name] = values
+	}
+
+	var report indexer.WorklogReport
+	if err := c.do(ctx, http.MethodGet, "/worklog/report", params, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// FlowMetrics - returns lead and cycle time of resolved issues matching the query and filters
+func (c *Client) FlowMetrics(ctx context.Context, query string, filters indexer.SearchFilters, opts indexer.FlowOptions) (*indexer.FlowReport, error) {
+	params := filters.Query()
+	if query != "" {
+		params.Set("q", query)
+	}
+	for name, values := range opts.Query() {
+		params[name] = values
+	}
+
+	var report indexer.FlowReport
+	if err := c.do(ctx, http.MethodGet, "/metrics/flow", params, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Inbox - returns issues where the user was summoned or mentioned and has not commented since
+func (c *Client) Inbox(ctx context.Context, opts indexer.InboxOptions) ([]indexer.InboxItem, error) {
+	var items []indexer.InboxItem
+	if err := c.do(ctx, http.MethodGet, "/inbox", opts.Query(), &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// FilterOptions - returns available filter values
+func (c *Client) FilterOptions(ctx context.Context) (*indexer.FilterOptions, error) {
+	var options indexer.FilterOptions
+	if err := c.do(ctx, http.MethodGet, "/filters", nil, &options); err != nil {
+		return nil, err
+	}
+	return &options, nil
+}
+
+// Dictionaries - returns synced Tracker dictionaries by kind
+func (c *Client) Dictionaries(ctx context.Context) (map[string][]tracker.DictionaryEntry, error) {
+	var dicts map[string][]tracker.DictionaryEntry
+	if err := c.do(ctx, http.MethodGet, "/dictionaries", nil, &dicts); err != nil {
+		return nil, err
+	}
+	return dicts, nil
+}
+
+// SyncStatus - returns current synchronization status
+func (c *Client) SyncStatus(ctx context.Context) (*sync.Status, error) {
+	var status sync.Status
+	if err := c.do(ctx, http.MethodGet, "/sync", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// TriggerSync - starts synchronization; errors.Is(err, ErrConflict) if it is already running
+func (c *Client) TriggerSync(ctx context.Context) (*sync.Status, error) {
+	var status sync.Status
+	if err := c.do(ctx, http.MethodPost, "/sync", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// CancelSync - cancels running synchronization; errors.Is(err, ErrConflict) if nothing is running
+func (c *Client) CancelSync(ctx context.Context) (*sync.Status, error) {
+	var status sync.Status
+	if err := c.do(ctx, http.MethodDelete, "/sync", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// ConfirmDeletions - removes stale issues kept by reconciliation above the deletion threshold;
+// errors.Is(err, ErrConflict) if nothing is pending or sync is running
+func (c *Client) ConfirmDeletions(ctx context.Context) (*sync.Status, error) {
+	var status sync.Status
+	if err := c.do(ctx, http.MethodPost, "/sync/deletions", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// SyncLogs - returns recent synchronization logs, newest first (limit 0 for all)
+func (c *Client) SyncLogs(ctx context.Context, limit int) ([]sync.LogEntry, error) {
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(limit))
+
+	var logs []sync.LogEntry
+	if err := c.do(ctx, http.MethodGet, "/sync/logs", params, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// do - performs an API request and decodes JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, params url.Values, out any) error {
+	u := c.baseURL + apiPrefix + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response body: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return parseError(resp.StatusCode, body)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// parseError - builds an *Error from an error response
+func parseError(statusCode int, body []byte) error {
+	var envelope struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	apiErr := &Error{StatusCode: statusCode}
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Error.Code != "" {
+		apiErr.Code = envelope.Error.Code
+		apiErr.Message = envelope.Error.Message
+	} else {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+
+	return apiErr
+}
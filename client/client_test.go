@@ -0,0 +1,280 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ytbs/indexer"
+	"ytbs/indexer/indexertest"
+	"ytbs/server"
+	"ytbs/sync"
+	"ytbs/tracker"
+)
+
+// testIssues - rows of the issues table served by the fake index
+var testIssues = []map[string]any{
+	{"id": 1, "issue_key": "TEST-1", "summary": "Login fails", "queue": "TEST", "status_name": "Open", "priority": "critical",
+		"created_at": 1700000000, "updated_at": 1700003600},
+	{"id": 2, "issue_key": "TEST-2", "summary": "Logout hangs", "queue": "TEST", "status_name": "Closed", "priority": "normal"},
+	{"id": 3, "issue_key": "OPS-1", "summary": "Disk is full", "queue": "OPS", "status_name": "Open", "priority": "normal"},
+	{"id": 4, "issue_key": "OPS-2", "summary": "Backup failed", "queue": "OPS", "status_name": "Open", "priority": "minor"},
+	{"id": 5, "issue_key": "WEB-1", "summary": "Broken link", "queue": "WEB", "status_name": "Open", "priority": "minor"},
+}
+
+var (
+	issuesTableRe = regexp.MustCompile(`FROM issues\s`)
+	keyEqualRe    = regexp.MustCompile(`issue_key = '([^']*)'`)
+	keyInRe       = regexp.MustCompile(`issue_key IN \(([^)]*)\)`)
+	groupByRe     = regexp.MustCompile(`GROUP BY (\w+)`)
+	limitRe       = regexp.MustCompile(`LIMIT (\d+)(?:, (\d+))?`)
+)
+
+// issueTable - answers queries of the issues table: counts, value counts, lookups by key
+// and pages of all issues. Other tables are empty
+func issueTable(issues []map[string]any) indexertest.Handler {
+	return func(sql string) ([]map[string]any, error) {
+		if !issuesTableRe.MatchString(sql) {
+			return nil, nil
+		}
+
+		if m := groupByRe.FindStringSubmatch(sql); m != nil {
+			var rows []map[string]any
+			counts := make(map[any]int)
+			for _, issue := range issues {
+				if counts[issue[m[1]]]++; counts[issue[m[1]]] == 1 {
+					rows = append(rows, map[string]any{m[1]: issue[m[1]]})
+				}
+			}
+			for _, row := range rows {
+				row["cnt"] = counts[row[m[1]]]
+			}
+			return rows, nil
+		}
+		if strings.Contains(sql, "COUNT(*)") {
+			return []map[string]any{{"cnt": len(issues)}}, nil
+		}
+
+		var keys []string
+		if m := keyEqualRe.FindStringSubmatch(sql); m != nil {
+			keys = []string{m[1]}
+		} else if m := keyInRe.FindStringSubmatch(sql); m != nil {
+			for _, k := range strings.Split(m[1], ",") {
+				keys = append(keys, strings.Trim(strings.TrimSpace(k), "'"))
+			}
+		} else if strings.Contains(sql, "WHERE id") || strings.Contains(sql, "key_ids") {
+			return nil, nil
+		}
+
+		var rows []map[string]any
+		for _, issue := range issues {
+			if keys == nil || containsString(keys, issue["issue_key"].(string)) {
+				rows = append(rows, issue)
+			}
+		}
+
+		if m := limitRe.FindStringSubmatch(sql); m != nil {
+			offset, limit := 0, 0
+			if m[2] == "" {
+				limit, _ = strconv.Atoi(m[1])
+			} else {
+				offset, _ = strconv.Atoi(m[1])
+				limit, _ = strconv.Atoi(m[2])
+			}
+			rows = rows[min(offset, len(rows)):min(offset+limit, len(rows))]
+		}
+		return rows, nil
+	}
+}
+
+// containsString - checks if the value is among the values
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// newTestClient - starts the API of an in-process server over a fake index and returns a client for it
+func newTestClient(t *testing.T, handler indexertest.Handler) (*Client, *indexertest.Server) {
+	t.Helper()
+	db := indexertest.NewServer(t, handler)
+	idx := indexer.NewIndexer(db.URL)
+	manager := sync.NewManager(tracker.NewClient("", ""), idx, tracker.SyncOptions{Workers: 1}, time.Hour)
+
+	srv, err := server.NewServer("", idx, manager)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	api := httptest.NewServer(srv.APIHandler())
+	t.Cleanup(api.Close)
+
+	return NewClient(api.URL+"/", nil), db
+}
+
+// queried - checks if any of the queries contains the fragment
+func queried(queries []string, fragment string) bool {
+	for _, q := range queries {
+		if strings.Contains(q, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSearch(t *testing.T) {
+	c, db := newTestClient(t, issueTable(testIssues))
+
+	filters := indexer.SearchFilters{Queue: indexer.FieldFilter{Values: []string{"WEB", "DOCS"}, Op: indexer.OpNotIn}}
+	page, err := c.Search(context.Background(), "login", filters,
+		SearchOptions{Offset: 2, Limit: 2, Facets: []string{"queue"}})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	if page.Total != len(testIssues) || page.Offset != 2 || page.Limit != 2 {
+		t.Errorf("total, offset, limit = %d, %d, %d, want %d, 2, 2", page.Total, page.Offset, page.Limit, len(testIssues))
+	}
+	if len(page.Results) != 2 || page.Results[0].Key != "OPS-1" || page.Results[1].Key != "OPS-2" {
+		t.Errorf("unexpected results %+v", page.Results)
+	}
+	if got := page.Facets["queue"]; len(got) != 3 || got[0].Value != "TEST" || got[0].Count != 2 {
+		t.Errorf("unexpected queue facet %+v", got)
+	}
+
+	queries := db.Queries()
+	for _, fragment := range []string{"MATCH('login')", "queue NOT IN ('WEB', 'DOCS')", "LIMIT 2, 2"} {
+		if !queried(queries, fragment) {
+			t.Errorf("no query with %q in %q", fragment, queries)
+		}
+	}
+}
+
+func TestFacets(t *testing.T) {
+	c, _ := newTestClient(t, issueTable(testIssues))
+
+	facets, err := c.Facets(context.Background(), "", indexer.SearchFilters{}, "queue", "status")
+	if err != nil {
+		t.Fatalf("Facets: %v", err)
+	}
+	if len(facets["queue"]) != 3 {
+		t.Errorf("unexpected queue facet %+v", facets["queue"])
+	}
+	if _, ok := facets["status"]; !ok {
+		t.Errorf("no status facet in %+v", facets)
+	}
+}
+
+func TestIssue(t *testing.T) {
+	c, _ := newTestClient(t, issueTable(testIssues))
+
+	issue, err := c.Issue(context.Background(), "TEST-1")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if issue.Key != "TEST-1" || issue.Summary != "Login fails" || issue.Queue != "TEST" {
+		t.Errorf("unexpected issue %+v", issue)
+	}
+	if want := time.Unix(1700000000, 0); !issue.CreatedAt.Equal(want) {
+		t.Errorf("created at %v, want %v", issue.CreatedAt, want)
+	}
+
+	_, err = c.Issue(context.Background(), "NOPE-1")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("error %v, want ErrNotFound", err)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "not_found" {
+		t.Errorf("unexpected error %+v", err)
+	}
+}
+
+func TestFilterOptions(t *testing.T) {
+	c, _ := newTestClient(t, issueTable(testIssues))
+
+	options, err := c.FilterOptions(context.Background())
+	if err != nil {
+		t.Fatalf("FilterOptions: %v", err)
+	}
+	if len(options.Queues) != 3 || !containsString(options.Queues, "OPS") {
+		t.Errorf("unexpected queues %v", options.Queues)
+	}
+}
+
+func TestSync(t *testing.T) {
+	c, _ := newTestClient(t, issueTable(testIssues))
+	ctx := context.Background()
+
+	status, err := c.SyncStatus(ctx)
+	if err != nil {
+		t.Fatalf("SyncStatus: %v", err)
+	}
+	if status.InProgress {
+		t.Errorf("sync is in progress before it was started")
+	}
+
+	if _, err := c.CancelSync(ctx); !errors.Is(err, ErrConflict) {
+		t.Errorf("CancelSync error %v, want ErrConflict", err)
+	}
+
+	if _, err := c.TriggerSync(ctx); err != nil {
+		t.Fatalf("TriggerSync: %v", err)
+	}
+
+	logs, err := c.SyncLogs(ctx, 10)
+	if err != nil {
+		t.Fatalf("SyncLogs: %v", err)
+	}
+	if len(logs) == 0 || logs[0].Message != "Manual sync triggered" {
+		t.Errorf("unexpected logs %+v", logs)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	failing := func(sql string) ([]map[string]any, error) {
+		return nil, fmt.Errorf("index is unavailable")
+	}
+
+	tests := []struct {
+		name    string
+		handler indexertest.Handler
+		opts    SearchOptions
+		status  int
+		code    string
+		target  error
+	}{
+		{"bad request", issueTable(testIssues), SearchOptions{Facets: []string{"summary"}},
+			http.StatusBadRequest, "bad_request", ErrBadRequest},
+		{"index failure", failing, SearchOptions{},
+			http.StatusInternalServerError, "search_failed", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t, tt.handler)
+
+			_, err := c.Search(context.Background(), "", indexer.SearchFilters{}, tt.opts)
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error %v is not *Error", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Code != tt.code || apiErr.Message == "" {
+				t.Errorf("unexpected error %+v", apiErr)
+			}
+			for _, sentinel := range []error{ErrBadRequest, ErrNotFound, ErrConflict} {
+				if got, want := errors.Is(err, sentinel), sentinel == tt.target; got != want {
+					t.Errorf("errors.Is(err, %v) = %v, want %v", sentinel, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// sentinel errors matched by errors.Is against *Error
+var (
+	ErrBadRequest = errors.New("bad request")
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+)
+
+// Error - error returned by the API
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+// Error - implements error
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("API error %d (%s): %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Message)
+}
+
+// Is - matches the error against ErrBadRequest, ErrNotFound and ErrConflict by status code
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	}
+	return false
+}
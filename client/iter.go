@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"iter"
+
+	"ytbs/indexer"
+)
+
+// defaultPageSize - page size used by SearchAll
+const defaultPageSize = 100
+
+// SearchAll - iterates over all results of a search, fetching pages lazily.
+// Iteration stops after the first error, which is yielded with an empty result
+func (c *Client) SearchAll(ctx context.Context, query string, filters indexer.SearchFilters, pageSize int) iter.Seq2[indexer.SearchResult, error] {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	// grouped responses hold only the top matches of each group
+	filters.GroupBy = ""
+
+	return func(yield func(indexer.SearchResult, error) bool) {
+		offset := 0
+		for {
+			page, err := c.Search(ctx, query, filters, SearchOptions{Offset: offset, Limit: pageSize})
+			if err != nil {
+				yield(indexer.SearchResult{}, err)
+				return
+			}
+
+			for _, r := range page.Results {
+				if !yield(r, nil) {
+					return
+				}
+			}
+
+			offset += len(page.Results)
+			if len(page.Results) == 0 || offset >= page.Total {
+				return
+			}
+		}
+	}
+}
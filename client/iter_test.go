@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"ytbs/indexer"
+)
+
+func TestSearchAll(t *testing.T) {
+	c, db := newTestClient(t, issueTable(testIssues))
+
+	var keys []string
+	for r, err := range c.SearchAll(context.Background(), "", indexer.SearchFilters{GroupBy: "queue"}, 2) {
+		if err != nil {
+			t.Fatalf("SearchAll: %v", err)
+		}
+		keys = append(keys, r.Key)
+	}
+
+	if len(keys) != len(testIssues) {
+		t.Fatalf("keys = %v, want all %d issues", keys, len(testIssues))
+	}
+	for i, issue := range testIssues {
+		if keys[i] != issue["issue_key"] {
+			t.Errorf("key %d = %s, want %s", i, keys[i], issue["issue_key"])
+		}
+	}
+
+	// grouped responses hold only the top matches of each group, so the grouping is dropped
+	if queried(db.Queries(), "GROUP BY") {
+		t.Errorf("SearchAll requested grouped results")
+	}
+	for _, page := range []string{"LIMIT 0, 2", "LIMIT 2, 2", "LIMIT 4, 2"} {
+		if !queried(db.Queries(), page) {
+			t.Errorf("page %q was not requested", page)
+		}
+	}
+}
+
+func TestSearchAllStop(t *testing.T) {
+	c, db := newTestClient(t, issueTable(testIssues))
+
+	var n int
+	for range c.SearchAll(context.Background(), "", indexer.SearchFilters{}, 3) {
+		if n++; n == 2 {
+			break
+		}
+	}
+	if queried(db.Queries(), "LIMIT 3, 3") {
+		t.Errorf("the next page was requested after the iteration stopped")
+	}
+}
+
+func TestSearchAllError(t *testing.T) {
+	pages := issueTable(testIssues)
+	c, _ := newTestClient(t, func(sql string) ([]map[string]any, error) {
+		if strings.Contains(sql, "LIMIT 2, 2") {
+			return nil, fmt.Errorf("index is unavailable")
+		}
+		return pages(sql)
+	})
+
+	var keys []string
+	var errs []error
+	for r, err := range c.SearchAll(context.Background(), "", indexer.SearchFilters{}, 2) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		keys = append(keys, r.Key)
+	}
+
+	if len(keys) != 2 {
+		t.Errorf("got %d results before the error, want 2", len(keys))
+	}
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1", len(errs))
+	}
+	var apiErr *Error
+	if !errors.As(errs[0], &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("unexpected error %v", errs[0])
+	}
+}
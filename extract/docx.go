@@ -0,0 +1,74 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// docxText - extracts text of the main document part of a .docx file
+func docxText(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("open docx: %w", err)
+	}
+
+	for _, f := range archive.File {
+		if f.Name != "word/document.xml" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return "", fmt.Errorf("open document part: %w", err)
+		}
+		defer r.Close()
+		return wordprocessingText(io.LimitReader(r, 64*MaxTextSize))
+	}
+
+	return "", fmt.Errorf("docx: no document part")
+}
+
+// wordprocessingText - collects text runs of WordprocessingML, one paragraph per line
+func wordprocessingText(r io.Reader) (string, error) {
+	var b strings.Builder
+	decoder := xml.NewDecoder(r)
+	inText := false
+
+	for b.Len() < MaxTextSize {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("parse document part: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteString("\t")
+			case "br", "cr":
+				b.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				b.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,90 @@
+// Package extract converts attachment files to plain text for indexing
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxTextSize - upper bound of extracted text size in bytes, the rest is dropped
+const MaxTextSize = 1 << 20
+
+// ErrUnsupported - file format has no text extractor
+var ErrUnsupported = errors.New("unsupported format")
+
+// extractor - converts file contents to text
+type extractor func(data []byte) (string, error)
+
+// extractors - extractors by file extension
+var extractors = map[string]extractor{
+	".pdf":  pdfText,
+	".docx": docxText,
+}
+
+// textExtensions - extensions of plain text files
+var textExtensions = map[string]bool{
+	".txt": true, ".log": true, ".md": true, ".csv": true, ".tsv": true,
+	".json": true, ".xml": true, ".yaml": true, ".yml": true, ".toml": true,
+	".ini": true, ".conf": true, ".cfg": true, ".properties": true, ".env": true,
+	".sql": true, ".sh": true, ".py": true, ".go": true, ".java": true,
+	".js": true, ".ts": true, ".html": true, ".htm": true, ".diff": true, ".patch": true,
+}
+
+// find - returns the extractor for a file name and MIME type
+func find(name, mimetype string) extractor {
+	ext := strings.ToLower(path.Ext(name))
+	if e, ok := extractors[ext]; ok {
+		return e
+	}
+	if textExtensions[ext] || strings.HasPrefix(mimetype, "text/") {
+		return plainText
+	}
+	return nil
+}
+
+// Supported - checks if text can be extracted from the file, so that it is worth downloading
+func Supported(name, mimetype string) bool {
+	return find(name, mimetype) != nil
+}
+
+// Text - extracts plain text from the file contents
+func Text(name, mimetype string, data []byte) (string, error) {
+	e := find(name, mimetype)
+	if e == nil {
+		return "", ErrUnsupported
+	}
+
+	text, err := e(data)
+	if err != nil {
+		return "", err
+	}
+	return truncate(strings.TrimSpace(text), MaxTextSize), nil
+}
+
+// plainText - returns text file contents, rejecting binary data
+func plainText(data []byte) (string, error) {
+	head := data
+	if len(head) > 8192 {
+		head = head[:8192]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return "", ErrUnsupported
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	return strings.ToValidUTF8(string(data), ""), nil
+}
+
+// truncate - cuts the text to at most size bytes without splitting a character
+func truncate(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	for size > 0 && !utf8.RuneStart(s[size]) {
+		size--
+	}
+	return s[:size]
+}
@@ -0,0 +1,470 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// pdfText - extracts text drawn by content streams of a PDF file.
+// This is a best-effort extractor: it handles uncompressed and Flate streams and
+// ToUnicode maps, but not encrypted files, images of text or custom font encodings
+func pdfText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data[:min(len(data), 1024)]), []byte("%PDF")) {
+		return "", fmt.Errorf("pdf: missing header")
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", fmt.Errorf("pdf: encrypted files are not supported")
+	}
+
+	var contents [][]byte
+	cmaps := make(pdfCMaps)
+	for _, stream := range pdfStreams(data) {
+		switch {
+		case bytes.Contains(stream, []byte("begincmap")):
+			cmaps.parse(stream)
+		case bytes.Contains(stream, []byte("BT")):
+			contents = append(contents, stream)
+		}
+	}
+
+	var b strings.Builder
+	for _, content := range contents {
+		if b.Len() >= MaxTextSize {
+			break
+		}
+		pdfContentText(content, cmaps, &b)
+		b.WriteString("\n")
+	}
+
+	return cleanPDFText(b.String()), nil
+}
+
+// pdfStreams - returns decoded streams that may contain text or character maps
+func pdfStreams(data []byte) [][]byte {
+	var streams [][]byte
+	keyword := []byte("stream")
+
+	for pos := 0; ; {
+		i := bytes.Index(data[pos:], keyword)
+		if i < 0 {
+			break
+		}
+		i += pos
+		pos = i + len(keyword)
+
+		// "endstream" contains the keyword as well
+		if i >= 3 && string(data[i-3:i]) == "end" {
+			continue
+		}
+
+		start := pos
+		if start < len(data) && data[start] == '\r' {
+			start++
+		}
+		if start < len(data) && data[start] == '\n' {
+			start++
+		}
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		end += start
+		pos = end
+
+		dictStart := bytes.LastIndex(data[:i], []byte("obj"))
+		if dictStart < 0 {
+			dictStart = 0
+		}
+		dict := data[dictStart:i]
+
+		if stream, ok := decodePDFStream(dict, data[start:end]); ok {
+			streams = append(streams, stream)
+		}
+	}
+
+	return streams
+}
+
+// skippedStreams - markers of streams without text: images, embedded fonts, metadata
+var skippedStreams = [][]byte{
+	[]byte("/Image"), []byte("/DCTDecode"), []byte("/JPXDecode"), []byte("/CCITTFaxDecode"),
+	[]byte("/JBIG2Decode"), []byte("/FontFile"), []byte("/Length1"), []byte("/Length2"),
+	[]byte("/Subtype/XML"), []byte("/Subtype /XML"),
+}
+
+// decodePDFStream - decodes stream data according to its dictionary
+func decodePDFStream(dict, raw []byte) ([]byte, bool) {
+	for _, marker := range skippedStreams {
+		if bytes.Contains(dict, marker) {
+			return nil, false
+		}
+	}
+
+	if !bytes.Contains(dict, []byte("/Filter")) {
+		return raw, true
+	}
+	// only a single Flate filter is supported
+	if !bytes.Contains(dict, []byte("/FlateDecode")) || bytes.Count(dict, []byte("Decode")) > 1 {
+		return nil, false
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+
+	// truncated streams still give useful text
+	decoded, _ := io.ReadAll(io.LimitReader(r, 16*MaxTextSize))
+	return decoded, len(decoded) > 0
+}
+
+// pdfCMaps - ToUnicode character maps of all fonts by code length in bytes.
+// Fonts are not told apart, so codes of different fonts may clash
+type pdfCMaps map[int]map[uint32]string
+
+var (
+	cmapBlockRe = regexp.MustCompile(`(?s)begin(bfchar|bfrange)(.*?)end(?:bfchar|bfrange)`)
+	cmapTokenRe = regexp.MustCompile(`<([0-9A-Fa-f]*)>|\[|\]`)
+)
+
+// parse - adds mappings of a CMap stream
+func (m pdfCMaps) parse(stream []byte) {
+	for _, block := range cmapBlockRe.FindAllSubmatch(stream, -1) {
+		var tokens []string
+		for _, t := range cmapTokenRe.FindAllSubmatch(block[2], -1) {
+			if t[0][0] == '<' {
+				tokens = append(tokens, string(t[1]))
+			} else {
+				tokens = append(tokens, string(t[0]))
+			}
+		}
+
+		if string(block[1]) == "bfchar" {
+			for i := 0; i+1 < len(tokens); i += 2 {
+				m.add(tokens[i], 0, utf16Hex(tokens[i+1]))
+			}
+			continue
+		}
+
+		for i := 0; i+2 < len(tokens); {
+			lo, hi := tokens[i], tokens[i+1]
+			from, _ := strconv.ParseUint(lo, 16, 32)
+			to, _ := strconv.ParseUint(hi, 16, 32)
+			if to < from || to-from > 0xffff {
+				break
+			}
+
+			if tokens[i+2] == "[" {
+				j := i + 3
+				for n := uint64(0); j < len(tokens) && tokens[j] != "]"; j, n = j+1, n+1 {
+					m.add(lo, uint32(n), utf16Hex(tokens[j]))
+				}
+				i = j + 1
+				continue
+			}
+
+			dst := []rune(utf16Hex(tokens[i+2]))
+			for n := uint64(0); n <= to-from && len(dst) > 0; n++ {
+				last := len(dst) - 1
+				shifted := append(append([]rune{}, dst[:last]...), dst[last]+rune(n))
+				m.add(lo, uint32(n), string(shifted))
+			}
+			i += 3
+		}
+	}
+}
+
+// add - maps the code (hex source code plus offset) to text
+func (m pdfCMaps) add(hexCode string, offset uint32, text string) {
+	code, err := strconv.ParseUint(hexCode, 16, 32)
+	if err != nil || len(hexCode) == 0 {
+		return
+	}
+	width := (len(hexCode) + 1) / 2
+	if m[width] == nil {
+		m[width] = make(map[uint32]string)
+	}
+	m[width][uint32(code)+offset] = text
+}
+
+// decode - decodes a string with the character maps if all its codes are known
+func (m pdfCMaps) decode(s []byte) (string, bool) {
+	for _, width := range []int{2, 1} {
+		codes := m[width]
+		if len(codes) == 0 || len(s)%width != 0 {
+			continue
+		}
+
+		var b strings.Builder
+		ok := true
+		for i := 0; i < len(s) && ok; i += width {
+			var code uint32
+			for _, c := range s[i : i+width] {
+				code = code<<8 | uint32(c)
+			}
+			var text string
+			text, ok = codes[code]
+			b.WriteString(text)
+		}
+		if ok {
+			return b.String(), true
+		}
+	}
+	return "", false
+}
+
+// utf16Hex - decodes hex encoded UTF-16BE text
+func utf16Hex(h string) string {
+	raw := hexBytes([]byte(h))
+	units := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// hexBytes - decodes a hex string ignoring whitespace; an odd last digit is padded with 0
+func hexBytes(h []byte) []byte {
+	var digits []byte
+	for _, c := range h {
+		if unicode.Is(unicode.ASCII_Hex_Digit, rune(c)) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		v, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		out[i] = byte(v)
+	}
+	return out
+}
+
+// pdfOperand - operand of a content stream operator
+type pdfOperand struct {
+	str    []byte
+	isStr  bool
+	num    float64
+	array  []pdfOperand
+	isList bool
+}
+
+// pdfContentText - writes text shown by the text operators of a content stream
+func pdfContentText(content []byte, cmaps pdfCMaps, b *strings.Builder) {
+	var operands []pdfOperand
+	var stack [][]pdfOperand // open arrays
+
+	push := func(op pdfOperand) {
+		if len(stack) > 0 {
+			stack[len(stack)-1] = append(stack[len(stack)-1], op)
+		} else {
+			operands = append(operands, op)
+		}
+	}
+	show := func(op pdfOperand) {
+		b.WriteString(pdfString(op.str, cmaps))
+	}
+
+	for i := 0; i < len(content) && b.Len() < MaxTextSize; {
+		c := content[i]
+		switch {
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, next := literalString(content, i)
+			push(pdfOperand{str: s, isStr: true})
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2
+		case c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				return
+			}
+			push(pdfOperand{str: hexBytes(content[i+1 : i+end]), isStr: true})
+			i += end + 1
+		case c == '[':
+			stack = append(stack, nil)
+			i++
+		case c == ']':
+			if len(stack) > 0 {
+				array := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				push(pdfOperand{array: array, isList: true})
+			}
+			i++
+		case c == '/':
+			i++
+			for i < len(content) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			push(pdfOperand{})
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(content) && (content[j] == '.' || (content[j] >= '0' && content[j] <= '9')) {
+				j++
+			}
+			n, _ := strconv.ParseFloat(string(content[i:j]), 64)
+			push(pdfOperand{num: n})
+			i = j
+		case isPDFDelimiter(c):
+			i++
+		default:
+			j := i
+			for j < len(content) && !isPDFDelimiter(content[j]) {
+				j++
+			}
+			op := string(content[i:j])
+			i = j
+
+			switch op {
+			case "Tj":
+				if n := len(operands); n > 0 {
+					show(operands[n-1])
+				}
+			case "'", "\"":
+				b.WriteString("\n")
+				if n := len(operands); n > 0 {
+					show(operands[n-1])
+				}
+			case "TJ":
+				if n := len(operands); n > 0 {
+					for _, item := range operands[n-1].array {
+						if item.isStr {
+							show(item)
+						} else if item.num < -200 {
+							// large negative kerning separates words
+							b.WriteString(" ")
+						}
+					}
+				}
+			case "T*", "ET":
+				b.WriteString("\n")
+			case "Td", "TD":
+				if n := len(operands); n >= 2 && operands[n-1].num != 0 {
+					b.WriteString("\n")
+				} else {
+					b.WriteString(" ")
+				}
+			case "Tm":
+				b.WriteString("\n")
+			}
+			operands = operands[:0]
+		}
+	}
+}
+
+// isPDFDelimiter - checks if the byte ends a PDF token
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// literalString - parses a literal string starting at the opening parenthesis,
+// returning its bytes and the position after it
+func literalString(content []byte, start int) ([]byte, int) {
+	var s []byte
+	depth := 0
+	for i := start; i < len(content); i++ {
+		c := content[i]
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return s, i + 1
+			}
+		case '\\':
+			i++
+			if i >= len(content) {
+				return s, i
+			}
+			switch e := content[i]; e {
+			case 'n':
+				s = append(s, '\n')
+			case 'r':
+				s = append(s, '\r')
+			case 't':
+				s = append(s, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					j := i
+					for j < len(content) && j < i+3 && content[j] >= '0' && content[j] <= '7' {
+						j++
+					}
+					v, _ := strconv.ParseUint(string(content[i:j]), 8, 8)
+					s = append(s, byte(v))
+					i = j - 1
+				} else {
+					s = append(s, e)
+				}
+			}
+			continue
+		}
+		s = append(s, c)
+	}
+	return s, len(content)
+}
+
+// pdfString - decodes a shown string: by character maps, as UTF-16 with BOM or as Latin-1
+func pdfString(s []byte, cmaps pdfCMaps) string {
+	if text, ok := cmaps.decode(s); ok {
+		return text
+	}
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+
+	runes := make([]rune, len(s))
+	for i, c := range s {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// cleanPDFText - drops control characters and collapses blank lines
+func cleanPDFText(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.Map(func(r rune) rune {
+			if r == '\t' || !unicode.IsControl(r) {
+				return r
+			}
+			return -1
+		}, line)
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
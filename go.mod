@@ -2,7 +2,6 @@ module ytbs
 
 go 1.25.5
 
-require (
-	github.com/manticoresoftware/manticoresearch-go v1.10.1-0.20251113092402-b8a6463603b5 // indirect
-	gopkg.in/validator.v2 v2.0.1 // indirect
-)
+require github.com/manticoresoftware/manticoresearch-go v1.10.1-0.20251113092402-b8a6463603b5
+
+require gopkg.in/validator.v2 v2.0.1 // indirect
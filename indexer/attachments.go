@@ -0,0 +1,174 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"ytbs/tracker"
+)
+
+// maxAttachmentHits - upper bound of attachment hits attributed to one page of results
+const maxAttachmentHits = 100
+
+// indexAttachments - replaces stored attachments of an issue
+func (idx *Indexer) indexAttachments(ctx context.Context, issueKey string, attachments []tracker.IndexedAttachment) error {
+	deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE issue_key = '%s'`, attachmentsTableName, escapeSQL(issueKey))
+	if _, err := idx.queryRows(ctx, deleteSQL); err != nil {
+		return fmt.Errorf("delete attachments of %s: %w", issueKey, err)
+	}
+
+	// files are stored one by one, since extracted texts may be large
+	for _, a := range attachments {
+		id, err := strconv.ParseInt(a.ID, 10, 64)
+		if err != nil {
+			id = documentID(issueKey + "|" + a.ID)
+		}
+
+		var doc document
+		doc.int("id", id)
+		doc.str("issue_key", issueKey)
+		doc.str("attachment_id", a.ID)
+		doc.str("name", a.Name)
+		doc.str("file_name", a.Name)
+		doc.str("mimetype", a.Mimetype)
+		doc.int("size", a.Size)
+		doc.str("author", a.Author)
+		doc.str("author_name", a.AuthorName)
+		doc.time("created_at", a.CreatedAt)
+		doc.str("content_hash", a.ContentHash)
+		doc.str("text", a.Text)
+
+		if _, err := idx.queryRows(ctx, replaceSQL(attachmentsTableName, []document{doc})); err != nil {
+			return fmt.Errorf("replace attachment %s of %s: %w", a.Name, issueKey, err)
+		}
+	}
+	return nil
+}
+
+// attachmentColumns - issue columns with attachment names and texts
+func attachmentColumns(attachments []tracker.IndexedAttachment) (names, texts string) {
+	var nameList, textList []string
+	for _, a := range attachments {
+		nameList = append(nameList, a.Name)
+		if a.Text != "" {
+			textList = append(textList, a.Text)
+		}
+	}
+	return strings.Join(nameList, "\n"), strings.Join(textList, "\n\n")
+}
+
+// AttachmentByID - returns the content hash and text of a downloaded attachment
+func (idx *Indexer) AttachmentByID(ctx context.Context, id string) (string, string, bool, error) {
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT content_hash, text FROM %s WHERE attachment_id = '%s' AND content_hash != '' LIMIT 1`,
+		attachmentsTableName, escapeSQL(id)))
+	if err != nil {
+		return "", "", false, fmt.Errorf("cached attachment %s: %w", id, err)
+	}
+	if len(rows) == 0 {
+		return "", "", false, nil
+	}
+	return getStringFromMap(rows[0], "content_hash"), getStringFromMap(rows[0], "text"), true, nil
+}
+
+// AttachmentTextByHash - returns text extracted from a file with the same contents
+func (idx *Indexer) AttachmentTextByHash(ctx context.Context, hash string) (string, bool, error) {
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT text FROM %s WHERE content_hash = '%s' LIMIT 1`,
+		attachmentsTableName, escapeSQL(hash)))
+	if err != nil {
+		return "", false, fmt.Errorf("cached attachment text %s: %w", hash, err)
+	}
+	if len(rows) == 0 {
+		return "", false, nil
+	}
+	return getStringFromMap(rows[0], "text"), true, nil
+}
+
+// GetAttachments - returns stored attachments of an issue without their texts
+func (idx *Indexer) GetAttachments(ctx context.Context, issueKey string) ([]tracker.IndexedAttachment, error) {
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT attachment_id, file_name, mimetype, size, author, author_name, created_at, content_hash
+		 FROM %s WHERE issue_key = '%s' ORDER BY created_at ASC LIMIT 1000`,
+		attachmentsTableName, escapeSQL(issueKey)))
+	if err != nil {
+		return nil, fmt.Errorf("get attachments of %s: %w", issueKey, err)
+	}
+
+	attachments := make([]tracker.IndexedAttachment, 0, len(rows))
+	for _, row := range rows {
+		attachments = append(attachments, tracker.IndexedAttachment{
+			ID:          getStringFromMap(row, "attachment_id"),
+			Name:        getStringFromMap(row, "file_name"),
+			Mimetype:    getStringFromMap(row, "mimetype"),
+			Size:        int64(getIntFromMap(row, "size")),
+			Author:      getStringFromMap(row, "author"),
+			AuthorName:  getStringFromMap(row, "author_name"),
+			CreatedAt:   getTimeFromMap(row, "created_at"),
+			ContentHash: getStringFromMap(row, "content_hash"),
+		})
+	}
+	return attachments, nil
+}
+
+// AttachmentHit - attachment matching the search query
+type AttachmentHit struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Snippet string `json:"snippet"`
+}
+
+// attributeAttachments - finds which attachments of the found issues match the query
+func (idx *Indexer) attributeAttachments(ctx context.Context, query string, results []SearchResult) error {
+	if query == "" || len(results) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(results))
+	for i, r := range results {
+		keys[i] = r.Key
+	}
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT issue_key, attachment_id, file_name,
+		        HIGHLIGHT({before_match='%s', after_match='%s', limit=200}, 'text') as snippet
+		 FROM %s
+		 WHERE MATCH('%s') AND %s
+		 LIMIT %d`,
+		snippetMatchStart, snippetMatchEnd, attachmentsTableName, escapeQuery(query), FieldFilter{Values: uniqueValues(keys)}.condition("issue_key"), maxAttachmentHits))
+	if err != nil {
+		return fmt.Errorf("attachment hits: %w", err)
+	}
+
+	hits := make(map[string][]AttachmentHit)
+	for _, row := range rows {
+		key := getStringFromMap(row, "issue_key")
+		hits[key] = append(hits[key], AttachmentHit{
+			ID:      getStringFromMap(row, "attachment_id"),
+			Name:    getStringFromMap(row, "file_name"),
+			Snippet: escapeSnippet(getStringFromMap(row, "snippet")),
+		})
+	}
+	for i := range results {
+		results[i].Attachments = hits[results[i].Key]
+	}
+	return nil
+}
+
+// snippet match markers - plain text markers of query matches in attachment snippets,
+// replaced with <b> once the snippet is escaped
+const (
+	snippetMatchStart = "[[ytbs-match]]"
+	snippetMatchEnd   = "[[/ytbs-match]]"
+)
+
+// escapeSnippet - escapes the extracted attachment text of a snippet, which is served as HTML,
+// and marks the query matches with <b>
+func escapeSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, snippetMatchStart, "<b>")
+	return strings.ReplaceAll(snippet, snippetMatchEnd, "</b>")
+}
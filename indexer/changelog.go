@@ -0,0 +1,299 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ytbs/tracker"
+)
+
+// maxHistoryRows - upper bound of changelog rows read to resolve a history filter
+const maxHistoryRows = 100000
+
+// indexChanges - stores new changelog entries of an issue. Entries are immutable,
+// so stored ones are kept and only new ones are added. Entries are numbered in the changelog
+// order following the stored ones, since several entries may share the change time
+func (idx *Indexer) indexChanges(ctx context.Context, issueKey string, changes []tracker.IndexedChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	seq, err := idx.lastChangeSeq(ctx, issueKey)
+	if err != nil {
+		return err
+	}
+
+	docs := make([]document, 0, len(changes))
+	for i, c := range changes {
+		// field changes of one entry share its number
+		if i == 0 || c.ChangeID != changes[i-1].ChangeID {
+			seq++
+		}
+
+		var doc document
+		doc.int("id", documentID(issueKey+"|"+c.ChangeID+"|"+c.Field))
+		doc.str("issue_key", issueKey)
+		doc.str("change_id", c.ChangeID)
+		doc.int("change_seq", seq)
+		doc.str("change_type", c.Type)
+		doc.str("field", c.Field)
+		doc.str("field_name", c.FieldName)
+		doc.str("from_value", c.From)
+		doc.str("from_display", c.FromDisplay)
+		doc.str("to_value", c.To)
+		doc.str("to_display", c.ToDisplay)
+		doc.str("author", c.Author)
+		doc.str("author_name", c.AuthorName)
+		doc.time("changed_at", c.ChangedAt)
+		docs = append(docs, doc)
+	}
+
+	if _, err := idx.queryRows(ctx, replaceSQL(changesTableName, docs)); err != nil {
+		return fmt.Errorf("replace changes of %s: %w", issueKey, err)
+	}
+	return nil
+}
+
+// lastChangeSeq - returns the number of the last stored changelog entry of an issue, 0 if nothing is stored
+func (idx *Indexer) lastChangeSeq(ctx context.Context, issueKey string) (int64, error) {
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT change_seq FROM %s WHERE issue_key = '%s' ORDER BY change_seq DESC LIMIT 1`,
+		changesTableName, escapeSQL(issueKey)))
+	if err != nil {
+		return 0, fmt.Errorf("last change number of %s: %w", issueKey, err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return int64(getIntFromMap(rows[0], "change_seq")), nil
+}
+
+// LastChangeID - returns the ID of the last stored changelog entry of an issue,
+// empty if nothing is stored. Entries stored before they were numbered are ordered by time
+func (idx *Indexer) LastChangeID(ctx context.Context, issueKey string) (string, error) {
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT change_id FROM %s WHERE issue_key = '%s' ORDER BY change_seq DESC, changed_at DESC LIMIT 1`,
+		changesTableName, escapeSQL(issueKey)))
+	if err != nil {
+		return "", fmt.Errorf("last change of %s: %w", issueKey, err)
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+	return getStringFromMap(rows[0], "change_id"), nil
+}
+
+// GetChanges - returns stored changelog of an issue in chronological order
+func (idx *Indexer) GetChanges(ctx context.Context, issueKey string) ([]tracker.IndexedChange, error) {
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT * FROM %s WHERE issue_key = '%s' ORDER BY changed_at ASC, change_seq ASC LIMIT 1000 OPTION max_matches=1000`,
+		changesTableName, escapeSQL(issueKey)))
+	if err != nil {
+		return nil, fmt.Errorf("get changes of %s: %w", issueKey, err)
+	}
+
+	changes := make([]tracker.IndexedChange, 0, len(rows))
+	for _, row := range rows {
+		changes = append(changes, tracker.IndexedChange{
+			ChangeID:    getStringFromMap(row, "change_id"),
+			Type:        getStringFromMap(row, "change_type"),
+			Field:       getStringFromMap(row, "field"),
+			FieldName:   getStringFromMap(row, "field_name"),
+			From:        getStringFromMap(row, "from_value"),
+			FromDisplay: getStringFromMap(row, "from_display"),
+			To:          getStringFromMap(row, "to_value"),
+			ToDisplay:   getStringFromMap(row, "to_display"),
+			Author:      getStringFromMap(row, "author"),
+			AuthorName:  getStringFromMap(row, "author_name"),
+			ChangedAt:   getTimeFromMap(row, "changed_at"),
+		})
+	}
+	return changes, nil
+}
+
+// statusSince - returns the time the issue got its current status:
+// the last status change or the creation time
+func (idx *Indexer) statusSince(ctx context.Context, issue tracker.IndexedIssue) (time.Time, error) {
+	var since time.Time
+	for _, c := range issue.Changes {
+		if c.Field == tracker.FieldStatus && c.ChangedAt.After(since) {
+			since = c.ChangedAt
+		}
+	}
+	if !since.IsZero() {
+		return since, nil
+	}
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT changed_at FROM %s WHERE issue_key = '%s' AND field = '%s' ORDER BY changed_at DESC LIMIT 1`,
+		changesTableName, escapeSQL(issue.Key), tracker.FieldStatus))
+	if err != nil {
+		return since, fmt.Errorf("status change of %s: %w", issue.Key, err)
+	}
+	if len(rows) > 0 {
+		return getTimeFromMap(rows[0], "changed_at"), nil
+	}
+	return issue.CreatedAt, nil
+}
+
+// changeRow - stored field change used by history filters
+type changeRow struct {
+	key       string
+	from      string
+	to        string
+	changedAt time.Time
+}
+
+// queryChanges - reads changes of a field matching the conditions. With perIssue set,
+// only the first change of each issue in the given order (ASC or DESC) is returned
+func (idx *Indexer) queryChanges(ctx context.Context, field, order string, perIssue bool, conditions ...string) ([]changeRow, error) {
+	conditions = append([]string{fmt.Sprintf("field = '%s'", escapeSQL(field))}, conditions...)
+
+	groupBy := ""
+	if perIssue {
+		groupBy = "GROUP BY issue_key WITHIN GROUP ORDER BY changed_at " + order
+	}
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT issue_key, from_display, to_display, changed_at FROM %s WHERE %s %s LIMIT %d OPTION max_matches=%d`,
+		changesTableName, strings.Join(conditions, " AND "), groupBy, maxHistoryRows, maxHistoryRows))
+	if err != nil {
+		return nil, fmt.Errorf("query %s changes: %w", field, err)
+	}
+
+	changes := make([]changeRow, 0, len(rows))
+	for _, row := range rows {
+		changes = append(changes, changeRow{
+			key:       getStringFromMap(row, "issue_key"),
+			from:      getStringFromMap(row, "from_display"),
+			to:        getStringFromMap(row, "to_display"),
+			changedAt: getTimeFromMap(row, "changed_at"),
+		})
+	}
+	return changes, nil
+}
+
+// changeKeys - returns unique issue keys of the changes
+func changeKeys(changes []changeRow) []string {
+	keys := make([]string, 0, len(changes))
+	for _, c := range changes {
+		keys = append(keys, c.key)
+	}
+	return uniqueValues(keys)
+}
+
+// keysCondition - matches issues by keys, or nothing if there are no keys
+func keysCondition(keys []string) string {
+	if len(keys) == 0 {
+		return "id < 0"
+	}
+	return FieldFilter{Values: keys}.condition("issue_key")
+}
+
+// wasInStatusCondition - matches issues that had any of the statuses at some moment of the period.
+// An issue was in a status during the period if it:
+//   - has it now and got it before the period end;
+//   - was moved to it within the period;
+//   - had it at the period start, after a change or from creation
+func (idx *Indexer) wasInStatusCondition(ctx context.Context, f HistoryFilter) (string, error) {
+	from, to := f.Since, f.Until
+	if to.IsZero() {
+		to = time.Now()
+	}
+	statuses := FieldFilter{Values: f.WasStatus}
+	between := fmt.Sprintf("changed_at >= %d AND changed_at <= %d", from.Unix(), to.Unix())
+
+	current := fmt.Sprintf("(%s AND status_since <= %d)", statuses.condition("status_name"), to.Unix())
+
+	// moved to the status within the period
+	moved, err := idx.queryChanges(ctx, tracker.FieldStatus, "", false, statuses.condition("to_display"), between)
+	if err != nil {
+		return "", err
+	}
+	keys := changeKeys(moved)
+
+	// moved to the status before the period and still in it at the period start
+	before, err := idx.queryChanges(ctx, tracker.FieldStatus, "", false,
+		statuses.condition("to_display"), fmt.Sprintf("changed_at < %d", from.Unix()))
+	if err != nil {
+		return "", err
+	}
+	if len(before) > 0 {
+		latest, err := idx.queryChanges(ctx, tracker.FieldStatus, "DESC", true,
+			keysCondition(changeKeys(before)), fmt.Sprintf("changed_at < %d", from.Unix()))
+		if err != nil {
+			return "", err
+		}
+		for _, c := range latest {
+			if f.hasStatus(c.to) {
+				keys = append(keys, c.key)
+			}
+		}
+	}
+
+	// created in the status and left it after the period start
+	left, err := idx.queryChanges(ctx, tracker.FieldStatus, "", false,
+		statuses.condition("from_display"), fmt.Sprintf("changed_at >= %d", from.Unix()))
+	if err != nil {
+		return "", err
+	}
+	var initial []string
+	if len(left) > 0 {
+		earliest, err := idx.queryChanges(ctx, tracker.FieldStatus, "ASC", true, keysCondition(changeKeys(left)))
+		if err != nil {
+			return "", err
+		}
+		for _, c := range earliest {
+			if f.hasStatus(c.from) && !c.changedAt.Before(from) {
+				initial = append(initial, c.key)
+			}
+		}
+	}
+
+	conditions := []string{current}
+	if len(keys) > 0 {
+		conditions = append(conditions, keysCondition(uniqueValues(keys)))
+	}
+	if len(initial) > 0 {
+		conditions = append(conditions, fmt.Sprintf("(%s AND created_at <= %d)", keysCondition(initial), to.Unix()))
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", nil
+}
+
+// wasAssigneeCondition - matches issues that are or were assigned to any of the users
+func (idx *Indexer) wasAssigneeCondition(ctx context.Context, f HistoryFilter) (string, error) {
+	users := FieldFilter{Values: f.WasAssignee}
+	changes, err := idx.queryChanges(ctx, tracker.FieldAssignee, "", false,
+		fmt.Sprintf("(%s OR %s)", users.condition("from_display"), users.condition("to_display")))
+	if err != nil {
+		return "", err
+	}
+
+	conditions := []string{users.condition("assignee_name")}
+	if keys := changeKeys(changes); len(keys) > 0 {
+		conditions = append(conditions, keysCondition(keys))
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", nil
+}
+
+// historyConditions - resolves history filters to conditions on issues
+func (idx *Indexer) historyConditions(ctx context.Context, f HistoryFilter) ([]string, error) {
+	var conditions []string
+	if len(f.WasStatus) > 0 {
+		cond, err := idx.wasInStatusCondition(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+	}
+	if len(f.WasAssignee) > 0 {
+		cond, err := idx.wasAssigneeCondition(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+	}
+	return conditions, nil
+}
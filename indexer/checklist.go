@@ -0,0 +1,114 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ytbs/tracker"
+)
+
+// maxChecklistRows - upper bound of checklist rows read by a single query
+const maxChecklistRows = 10000
+
+// ChecklistFilter - filter on unchecked checklist items
+type ChecklistFilter struct {
+	// Open - issues with any unchecked item
+	Open bool `json:"open,omitempty"`
+	// Assignees - names or IDs of assignees of unchecked items
+	Assignees []string `json:"assignees,omitempty"`
+	// Overdue - issues with unchecked items past their deadline
+	Overdue bool `json:"overdue,omitempty"`
+}
+
+// IsSet - checks if the filter restricts results
+func (f ChecklistFilter) IsSet() bool {
+	return f.Open || len(f.Assignees) > 0 || f.Overdue
+}
+
+// indexChecklist - replaces stored checklist items of an issue
+func (idx *Indexer) indexChecklist(ctx context.Context, issueKey string, items []tracker.IndexedChecklistItem) error {
+	deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE issue_key = '%s'`, checklistTableName, escapeSQL(issueKey))
+	if _, err := idx.queryRows(ctx, deleteSQL); err != nil {
+		return fmt.Errorf("delete checklist of %s: %w", issueKey, err)
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	docs := make([]document, 0, len(items))
+	for i, item := range items {
+		checked := int64(0)
+		if item.Checked {
+			checked = 1
+		}
+
+		var doc document
+		doc.int("id", documentID(issueKey+"|"+item.ID))
+		doc.str("issue_key", issueKey)
+		doc.str("item_id", item.ID)
+		doc.int("position", int64(i))
+		doc.str("text", item.Text)
+		doc.int("checked", checked)
+		doc.str("assignee", item.Assignee)
+		doc.str("assignee_name", item.AssigneeName)
+		doc.time("deadline", item.Deadline)
+		docs = append(docs, doc)
+	}
+
+	if _, err := idx.queryRows(ctx, replaceSQL(checklistTableName, docs)); err != nil {
+		return fmt.Errorf("replace checklist of %s: %w", issueKey, err)
+	}
+	return nil
+}
+
+// GetChecklist - returns stored checklist items of an issue in their original order
+func (idx *Indexer) GetChecklist(ctx context.Context, issueKey string) ([]tracker.IndexedChecklistItem, error) {
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT * FROM %s WHERE issue_key = '%s' ORDER BY position ASC LIMIT 1000`,
+		checklistTableName, escapeSQL(issueKey)))
+	if err != nil {
+		return nil, fmt.Errorf("get checklist of %s: %w", issueKey, err)
+	}
+
+	items := make([]tracker.IndexedChecklistItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, tracker.IndexedChecklistItem{
+			ID:           getStringFromMap(row, "item_id"),
+			Text:         getStringFromMap(row, "text"),
+			Checked:      getIntFromMap(row, "checked") == 1,
+			Assignee:     getStringFromMap(row, "assignee"),
+			AssigneeName: getStringFromMap(row, "assignee_name"),
+			Deadline:     getTimeFromMap(row, "deadline"),
+		})
+	}
+	return items, nil
+}
+
+// checklistCondition - matches issues having unchecked items that satisfy the filter
+func (idx *Indexer) checklistCondition(ctx context.Context, f ChecklistFilter) (string, error) {
+	conditions := []string{"checked = 0"}
+	if len(f.Assignees) > 0 {
+		users := FieldFilter{Values: f.Assignees}
+		conditions = append(conditions,
+			fmt.Sprintf("(%s OR %s)", users.condition("assignee"), users.condition("assignee_name")))
+	}
+	if f.Overdue {
+		conditions = append(conditions, fmt.Sprintf("deadline > 0 AND deadline < %d", time.Now().Unix()))
+	}
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT issue_key FROM %s WHERE %s GROUP BY issue_key LIMIT %d OPTION max_matches=%d`,
+		checklistTableName, strings.Join(conditions, " AND "), maxChecklistRows, maxChecklistRows))
+	if err != nil {
+		return "", fmt.Errorf("query checklist items: %w", err)
+	}
+
+	keys := make([]string, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, getStringFromMap(row, "issue_key"))
+	}
+	return keysCondition(keys), nil
+}
@@ -0,0 +1,81 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ytbs/tracker"
+)
+
+// SyncOptions - sets the hooks of the sync options stored by the index: the changelog cursor,
+// the attachment text cache and the checkpoint issues are indexed with in batches
+func (idx *Indexer) SyncOptions(options tracker.SyncOptions) tracker.SyncOptions {
+	options.ChangelogCursor = idx.LastChangeID
+	options.Attachments.Cache = idx
+	options.Checkpoint = idx
+	options.OnBatch = idx.IndexSynced
+	return options
+}
+
+// Processed - returns keys of issues indexed by an interrupted sync with their update time then.
+// Implements tracker.SyncCheckpoint
+func (idx *Indexer) Processed(ctx context.Context) (map[string]time.Time, error) {
+	processed := make(map[string]time.Time)
+	for offset := 0; ; offset += scanPageSize {
+		rows, err := idx.queryRows(ctx, fmt.Sprintf(
+			`SELECT issue_key, updated_at FROM %s ORDER BY issue_key ASC LIMIT %d, %d OPTION max_matches=%d`,
+			checkpointTableName, offset, scanPageSize, offset+scanPageSize))
+		if err != nil {
+			return nil, fmt.Errorf("read sync checkpoint: %w", err)
+		}
+		for _, row := range rows {
+			processed[getStringFromMap(row, "issue_key")] = getTimeFromMap(row, "updated_at")
+		}
+		if len(rows) < scanPageSize {
+			return processed, nil
+		}
+	}
+}
+
+// IndexSynced - indexes issues processed by a running sync and records them in the checkpoint,
+// so that a restarted sync skips them. Issues with details that failed to load are not recorded,
+// so that a restarted sync loads them again
+func (idx *Indexer) IndexSynced(ctx context.Context, issues []tracker.IndexedIssue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+	if err := idx.indexBatch(ctx, issues); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	docs := make([]document, 0, len(issues))
+	for _, issue := range issues {
+		if !issue.Complete() {
+			continue
+		}
+		var doc document
+		doc.int("id", documentID(issue.Key))
+		doc.str("issue_key", issue.Key)
+		doc.time("updated_at", issue.UpdatedAt)
+		doc.time("processed_at", now)
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+
+	if _, err := idx.queryRows(ctx, replaceSQL(checkpointTableName, docs)); err != nil {
+		return fmt.Errorf("save sync checkpoint: %w", err)
+	}
+	return nil
+}
+
+// ClearCheckpoint - forgets the progress of a completed sync, so that the next one processes all issues
+func (idx *Indexer) ClearCheckpoint(ctx context.Context) error {
+	if _, err := idx.queryRows(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id > 0`, checkpointTableName)); err != nil {
+		return fmt.Errorf("clear sync checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"ytbs/tracker"
+)
+
+// indexComments - replaces stored comments of an issue
+func (idx *Indexer) indexComments(ctx context.Context, issueKey string, comments []tracker.IndexedComment) error {
+	deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE issue_key = '%s'`, commentsTableName, escapeSQL(issueKey))
+	if _, err := idx.queryRows(ctx, deleteSQL); err != nil {
+		return fmt.Errorf("delete comments of %s: %w", issueKey, err)
+	}
+
+	if len(comments) == 0 {
+		return nil
+	}
+
+	docs := make([]document, 0, len(comments))
+	for _, c := range comments {
+		var doc document
+		doc.int("id", c.ID)
+		doc.str("issue_key", issueKey)
+		doc.str("author", c.Author)
+		doc.str("author_name", c.AuthorName)
+		doc.str("text", c.Text)
+		doc.time("created_at", c.CreatedAt)
+		doc.time("updated_at", c.UpdatedAt)
+		docs = append(docs, doc)
+	}
+
+	if _, err := idx.queryRows(ctx, replaceSQL(commentsTableName, docs)); err != nil {
+		return fmt.Errorf("replace comments of %s: %w", issueKey, err)
+	}
+	return nil
+}
+
+// GetComments - returns stored comments of an issue in chronological order
+func (idx *Indexer) GetComments(ctx context.Context, issueKey string) ([]tracker.IndexedComment, error) {
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT * FROM %s WHERE issue_key = '%s' ORDER BY created_at ASC LIMIT 1000`,
+		commentsTableName, escapeSQL(issueKey)))
+	if err != nil {
+		return nil, fmt.Errorf("get comments of %s: %w", issueKey, err)
+	}
+
+	comments := make([]tracker.IndexedComment, 0, len(rows))
+	for _, row := range rows {
+		id, _ := strconv.ParseInt(getStringFromMap(row, "id"), 10, 64)
+		comments = append(comments, tracker.IndexedComment{
+			ID:         id,
+			Author:     getStringFromMap(row, "author"),
+			AuthorName: getStringFromMap(row, "author_name"),
+			Text:       getStringFromMap(row, "text"),
+			CreatedAt:  getTimeFromMap(row, "created_at"),
+			UpdatedAt:  getTimeFromMap(row, "updated_at"),
+		})
+	}
+	return comments, nil
+}
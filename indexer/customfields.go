@@ -0,0 +1,359 @@
+package indexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CustomFieldType - how a declared custom field is indexed
+type CustomFieldType string
+
+const (
+	// CustomText - full-text searchable field
+	CustomText CustomFieldType = "text"
+	// CustomString - exact value, filterable and facetable
+	CustomString CustomFieldType = "string"
+	// CustomNumber - number filterable by range and facetable
+	CustomNumber CustomFieldType = "number"
+	// CustomDate - date filterable by range
+	CustomDate CustomFieldType = "date"
+)
+
+// customColumnDefs - table column definitions of the field types
+var customColumnDefs = map[CustomFieldType]string{
+	CustomText:   "TEXT",
+	CustomString: "STRING",
+	CustomNumber: "FLOAT",
+	CustomDate:   "TIMESTAMP",
+}
+
+// CustomField - local or extra Tracker field declared for indexing
+type CustomField struct {
+	// ID - field key in the Tracker issue, e.g. storyPoints
+	ID   string          `json:"id"`
+	Type CustomFieldType `json:"type"`
+	// Name - display name, the ID if not declared
+	Name string `json:"name"`
+	// Param - URL parameter, facet name and table column of the field
+	Param string `json:"param"`
+}
+
+// customSetSuffix - suffix of the column marking issues that have a number field set,
+// as issues without the number are stored with zero
+const customSetSuffix = "_set"
+
+// customFields - declared custom fields, set once at startup
+var customFields []CustomField
+
+// nonParamRe - characters not allowed in parameter and column names
+var nonParamRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// customParam - derives the column name of a field, e.g. storyPoints -> cf_storypoints
+func customParam(id string) string {
+	return "cf_" + strings.Trim(nonParamRe.ReplaceAllString(strings.ToLower(id), "_"), "_")
+}
+
+// ParseCustomFields - parses field declarations "id:type[:name]" separated by commas,
+// e.g. "storyPoints:number:Story points,customer:string"
+func ParseCustomFields(spec string) ([]CustomField, error) {
+	var fields []CustomField
+	seen := make(map[string]string)
+
+	for _, decl := range strings.Split(spec, ",") {
+		decl = strings.TrimSpace(decl)
+		if decl == "" {
+			continue
+		}
+
+		parts := strings.SplitN(decl, ":", 3)
+		if len(parts) < 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("custom field %q: expected id:type[:name]", decl)
+		}
+
+		f := CustomField{
+			ID:   strings.TrimSpace(parts[0]),
+			Type: CustomFieldType(strings.TrimSpace(parts[1])),
+		}
+		if _, ok := customColumnDefs[f.Type]; !ok {
+			return nil, fmt.Errorf("custom field %s: unknown type %q, expected text, string, number or date", f.ID, f.Type)
+		}
+		f.Name = f.ID
+		if len(parts) == 3 && strings.TrimSpace(parts[2]) != "" {
+			f.Name = strings.TrimSpace(parts[2])
+		}
+		f.Param = customParam(f.ID)
+		if f.Param == "cf_" {
+			return nil, fmt.Errorf("custom field %q: ID has no letters or digits", f.ID)
+		}
+		if other, ok := seen[f.Param]; ok {
+			return nil, fmt.Errorf("custom fields %s and %s map to the same column %s", other, f.ID, f.Param)
+		}
+		seen[f.Param] = f.ID
+
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// SetCustomFields - declares custom fields to index; must be called before CreateTable
+func SetCustomFields(fields []CustomField) {
+	customFields = fields
+}
+
+// CustomFields - returns declared custom fields
+func CustomFields() []CustomField {
+	return customFields
+}
+
+// customField - returns the declared field with the parameter name
+func customField(param string) (CustomField, bool) {
+	for _, f := range customFields {
+		if f.Param == param {
+			return f, true
+		}
+	}
+	return CustomField{}, false
+}
+
+// customColumns - table columns of declared custom fields
+func customColumns() []column {
+	columns := make([]column, 0, len(customFields))
+	for _, f := range customFields {
+		columns = append(columns, column{f.Param, customColumnDefs[f.Type]})
+		if f.Type == CustomNumber {
+			columns = append(columns, column{f.Param + customSetSuffix, "INTEGER"})
+		}
+	}
+	return columns
+}
+
+// Facetable - checks if value counts of the field make sense
+func (f CustomField) Facetable() bool {
+	return f.Type == CustomString || f.Type == CustomNumber
+}
+
+// Display - formats the raw field value for display and string columns.
+// References are shown by display name, lists are joined with commas
+func (f CustomField) Display(raw json.RawMessage) string {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return ""
+	}
+	return displayValue(value)
+}
+
+// displayValue - formats a decoded JSON value
+func displayValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if s := displayValue(item); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, ", ")
+	case map[string]any:
+		for _, key := range []string{"display", "name", "key", "id"} {
+			if s, ok := v[key].(string); ok && s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// number - reads a numeric field value, given as a number or a numeric string
+func (f CustomField) number(raw json.RawMessage) (float64, bool) {
+	n, err := strconv.ParseFloat(f.Display(raw), 64)
+	return n, err == nil
+}
+
+// date - reads a date field value: a date or a Tracker timestamp
+func (f CustomField) date(raw json.RawMessage) time.Time {
+	s := f.Display(raw)
+	for _, layout := range []string{dateLayout, "2006-01-02T15:04:05.000-0700", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// addCustomFields - adds columns of declared fields to the issue document
+func addCustomFields(doc *document, values map[string]json.RawMessage) {
+	for _, f := range customFields {
+		raw := values[f.ID]
+		switch f.Type {
+		case CustomText, CustomString:
+			doc.str(f.Param, f.Display(raw))
+		case CustomNumber:
+			n, ok := f.number(raw)
+			doc.float(f.Param, n)
+			var set int64
+			if ok {
+				set = 1
+			}
+			doc.int(f.Param+customSetSuffix, set)
+		case CustomDate:
+			doc.time(f.Param, f.date(raw))
+		}
+	}
+}
+
+// CustomFilter - filter on a declared custom field
+type CustomFilter struct {
+	// FieldFilter - values and operator of string fields, words of text fields
+	FieldFilter
+	// From, To - range of number and date fields, open if empty
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// IsSet - checks if the filter restricts results
+func (f CustomFilter) IsSet() bool {
+	return f.FieldFilter.IsSet() || f.From != "" || f.To != ""
+}
+
+// parseCustomFilters - reads filters on declared fields: cf_x=value&cf_x_op=not for strings,
+// cf_x=words for texts, cf_x_from and cf_x_to for numbers and dates
+func parseCustomFilters(values url.Values) map[string]CustomFilter {
+	filters := make(map[string]CustomFilter)
+	for _, f := range customFields {
+		var cf CustomFilter
+		switch f.Type {
+		case CustomString:
+			// values of list fields are stored joined with commas, so they are not split
+			cf.Values = paramValues(values[f.Param])
+			switch op := FilterOp(values.Get(f.Param + "_op")); op {
+			case OpNotIn, OpEmpty, OpNotEmpty:
+				cf.Op = op
+			}
+		case CustomText:
+			if text := strings.TrimSpace(values.Get(f.Param)); text != "" {
+				cf.Values = []string{text}
+			}
+		case CustomNumber, CustomDate:
+			cf.From = strings.TrimSpace(values.Get(f.Param + "_from"))
+			cf.To = strings.TrimSpace(values.Get(f.Param + "_to"))
+		}
+		if cf.IsSet() {
+			filters[f.Param] = cf
+		}
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return filters
+}
+
+// customQuery - encodes custom field filters as URL parameters
+func customQuery(filters map[string]CustomFilter, values url.Values) {
+	for param, cf := range filters {
+		for _, v := range cf.Values {
+			values.Add(param, v)
+		}
+		if cf.Op != OpIn {
+			values.Set(param+"_op", string(cf.Op))
+		}
+		if cf.From != "" {
+			values.Set(param+"_from", cf.From)
+		}
+		if cf.To != "" {
+			values.Set(param+"_to", cf.To)
+		}
+	}
+}
+
+// customConditions - builds attribute conditions of custom field filters;
+// filters on text fields are part of the full-text match
+func customConditions(filters map[string]CustomFilter) []string {
+	var conditions []string
+	for _, f := range customFields {
+		cf, ok := filters[f.Param]
+		if !ok {
+			continue
+		}
+		switch f.Type {
+		case CustomString:
+			if cond := cf.condition(f.Param); cond != "" {
+				conditions = append(conditions, cond)
+			}
+		case CustomNumber:
+			var bounds []string
+			if n, err := strconv.ParseFloat(cf.From, 64); err == nil {
+				bounds = append(bounds, fmt.Sprintf("%s >= %s", f.Param, strconv.FormatFloat(n, 'f', -1, 64)))
+			}
+			if n, err := strconv.ParseFloat(cf.To, 64); err == nil {
+				bounds = append(bounds, fmt.Sprintf("%s <= %s", f.Param, strconv.FormatFloat(n, 'f', -1, 64)))
+			}
+			if len(bounds) > 0 {
+				// issues without the number are stored with zero
+				conditions = append(conditions, fmt.Sprintf("%s%s = 1", f.Param, customSetSuffix))
+				conditions = append(conditions, bounds...)
+			}
+		case CustomDate:
+			if from := parseDate(cf.From, false); !from.IsZero() {
+				conditions = append(conditions, fmt.Sprintf("%s >= %d", f.Param, from.Unix()))
+			}
+			if to := parseDate(cf.To, true); !to.IsZero() {
+				// issues without the date are stored with zero
+				conditions = append(conditions, fmt.Sprintf("%s > 0 AND %s <= %d", f.Param, f.Param, to.Unix()))
+			}
+		}
+	}
+	return conditions
+}
+
+// customMatch - full-text conditions on declared text fields, e.g. @cf_notes (words)
+func customMatch(filters map[string]CustomFilter) []string {
+	var parts []string
+	for _, f := range customFields {
+		cf, ok := filters[f.Param]
+		if !ok || f.Type != CustomText || len(cf.Values) == 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("@%s (%s)", f.Param, escapeQuery(strings.Join(cf.Values, " "))))
+	}
+	return parts
+}
+
+// CustomFieldOptions - declared field with its most frequent values
+type CustomFieldOptions struct {
+	CustomField
+	Values []string `json:"values,omitempty"`
+}
+
+// CustomValue - declared field value of an issue for display
+type CustomValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CustomValues - returns display values of declared fields set on the issue
+func CustomValues(values map[string]json.RawMessage) []CustomValue {
+	var result []CustomValue
+	for _, f := range customFields {
+		raw, ok := values[f.ID]
+		if !ok {
+			continue
+		}
+		if v := f.Display(raw); v != "" {
+			result = append(result, CustomValue{Name: f.Name, Value: v})
+		}
+	}
+	return result
+}
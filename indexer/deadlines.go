@@ -0,0 +1,104 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ytbs/tracker"
+)
+
+// maxCalendarEvents - upper bound of deadlines in a calendar feed
+const maxCalendarEvents = 1000
+
+// calendarPastDays - number of days before today whose deadlines stay in a calendar feed
+const calendarPastDays = 30
+
+// DeadlineFilter - filter on the issue deadline
+type DeadlineFilter string
+
+const (
+	// DeadlineOverdue - unfinished issues with the deadline before today
+	DeadlineOverdue DeadlineFilter = "overdue"
+	// DeadlineThisWeek - issues due from Monday to Sunday of the current week
+	DeadlineThisWeek DeadlineFilter = "week"
+	// DeadlineNone - issues without a deadline
+	DeadlineNone DeadlineFilter = "none"
+)
+
+// condition - builds the attribute condition of the filter relative to now
+func (f DeadlineFilter) condition(now time.Time) string {
+	now = now.Local()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	switch f {
+	case DeadlineOverdue:
+		return fmt.Sprintf("deadline > 0 AND deadline < %d AND status_category != '%s'",
+			today.Unix(), tracker.CategoryDone)
+	case DeadlineThisWeek:
+		monday := weekStart(now)
+		return fmt.Sprintf("deadline >= %d AND deadline < %d", monday.Unix(), monday.AddDate(0, 0, 7).Unix())
+	case DeadlineNone:
+		return "deadline = 0"
+	}
+	return ""
+}
+
+// Overdue - checks if an unfinished issue is past its deadline day
+func Overdue(deadline time.Time, statusCategory string, now time.Time) bool {
+	if deadline.IsZero() || statusCategory == tracker.CategoryDone {
+		return false
+	}
+	now = now.Local()
+	return deadline.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local))
+}
+
+// CalendarEvent - deadline of an issue for a calendar feed
+type CalendarEvent struct {
+	Key          string    `json:"key"`
+	URL          string    `json:"url"`
+	Summary      string    `json:"summary"`
+	StatusName   string    `json:"status_name"`
+	AssigneeName string    `json:"assignee_name"`
+	Deadline     time.Time `json:"deadline"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Calendar - returns deadlines of issues matching the query and filters from calendarPastDays
+// ago on, earliest first, so that old deadlines don't push upcoming ones out of the feed
+func (idx *Indexer) Calendar(ctx context.Context, query string, filters SearchFilters) ([]CalendarEvent, error) {
+	whereClause, err := idx.buildWhere(ctx, query, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day()-calendarPastDays, 0, 0, 0, 0, time.Local)
+	if whereClause == "" {
+		whereClause = fmt.Sprintf("WHERE deadline >= %d", since.Unix())
+	} else {
+		whereClause += fmt.Sprintf(" AND deadline >= %d", since.Unix())
+	}
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT issue_key, url, summary, status_name, assignee_name, deadline, updated_at
+		 FROM %s %s ORDER BY deadline ASC LIMIT %d OPTION max_matches=%d`,
+		tableName, whereClause, maxCalendarEvents, maxCalendarEvents))
+	if err != nil {
+		return nil, fmt.Errorf("calendar: %w", err)
+	}
+
+	events := make([]CalendarEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, CalendarEvent{
+			Key:          getStringFromMap(row, "issue_key"),
+			URL:          getStringFromMap(row, "url"),
+			Summary:      getStringFromMap(row, "summary"),
+			StatusName:   getStringFromMap(row, "status_name"),
+			AssigneeName: getStringFromMap(row, "assignee_name"),
+			Deadline:     getTimeFromMap(row, "deadline"),
+			UpdatedAt:    getTimeFromMap(row, "updated_at"),
+		})
+	}
+	return events, nil
+}
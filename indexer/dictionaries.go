@@ -0,0 +1,308 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ytbs/tracker"
+)
+
+// defaultLanguage - language of display names if not configured
+const defaultLanguage = "ru"
+
+// sprintArchived - status of sprints hidden from filter options
+const sprintArchived = "archived"
+
+// Dictionaries - cached Tracker dictionaries with names in the configured language
+type Dictionaries struct {
+	lang    string
+	entries map[string]map[string]tracker.DictionaryEntry
+}
+
+// newDictionaries - builds the lookup of dictionary entries by kind and key
+func newDictionaries(lang string, entries []tracker.DictionaryEntry) *Dictionaries {
+	d := &Dictionaries{
+		lang:    lang,
+		entries: make(map[string]map[string]tracker.DictionaryEntry),
+	}
+	for _, e := range entries {
+		if d.entries[e.Kind] == nil {
+			d.entries[e.Kind] = make(map[string]tracker.DictionaryEntry)
+		}
+		d.entries[e.Kind][e.Key] = e
+	}
+	return d
+}
+
+// Name - returns the localized name of a field value, empty if it is unknown
+func (d *Dictionaries) Name(kind, key string) string {
+	if d == nil {
+		return ""
+	}
+	return d.entries[kind][key].Names.In(d.lang)
+}
+
+// Category - returns the category of a status, empty if the status is unknown
+func (d *Dictionaries) Category(status string) string {
+	if d == nil {
+		return ""
+	}
+	return d.entries[tracker.DictStatus][status].Category
+}
+
+// Entries - returns entries of a dictionary in their Tracker order
+func (d *Dictionaries) Entries(kind string) []tracker.DictionaryEntry {
+	if d == nil {
+		return nil
+	}
+	entries := make([]tracker.DictionaryEntry, 0, len(d.entries[kind]))
+	for _, e := range d.entries[kind] {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Order != entries[j].Order {
+			return entries[i].Order < entries[j].Order
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// All - returns entries of all dictionaries by kind in their Tracker order
+func (d *Dictionaries) All() map[string][]tracker.DictionaryEntry {
+	all := make(map[string][]tracker.DictionaryEntry)
+	if d == nil {
+		return all
+	}
+	for kind := range d.entries {
+		all[kind] = d.Entries(kind)
+	}
+	return all
+}
+
+// less - compares values of a dictionary field by their Tracker order, unknown values last
+func (d *Dictionaries) less(kind, a, b string) bool {
+	if d == nil {
+		return false
+	}
+	ea, aok := d.entries[kind][a]
+	eb, bok := d.entries[kind][b]
+	if aok != bok {
+		return aok
+	}
+	return ea.Order < eb.Order
+}
+
+// order - returns the Tracker order of a dictionary value, 0 if it is unknown
+func (d *Dictionaries) order(kind, key string) int {
+	if d == nil {
+		return 0
+	}
+	return d.entries[kind][key].Order
+}
+
+// keys - returns keys of a dictionary in their Tracker order, without archived sprints
+func (d *Dictionaries) keys(kind string) []string {
+	var keys []string
+	for _, e := range d.Entries(kind) {
+		if kind == tracker.DictSprint && e.Status == sprintArchived {
+			continue
+		}
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+// names - returns localized names of the values that have one
+func (d *Dictionaries) names(kind string, values []string) map[string]string {
+	names := make(map[string]string)
+	for _, v := range values {
+		if name := d.Name(kind, v); name != "" {
+			names[v] = name
+		}
+	}
+	return names
+}
+
+// sortByOrder - sorts values of a dictionary field by their Tracker order, unknown values last
+func (d *Dictionaries) sortByOrder(kind string, values []string) {
+	sort.SliceStable(values, func(i, j int) bool {
+		return d.less(kind, values[i], values[j])
+	})
+}
+
+// facetDictionaries - dictionaries naming the values of facet fields
+var facetDictionaries = map[string]string{
+	"queue":            tracker.DictQueue,
+	"priority":         tracker.DictPriority,
+	"component":        tracker.DictComponent,
+	"fix_version":      tracker.DictVersion,
+	"affected_version": tracker.DictVersion,
+	"sprint":           tracker.DictSprint,
+}
+
+// applyDictionaries - replaces display names of the issue with localized ones and sets
+// the status category; values missing from the dictionaries keep their API names
+func applyDictionaries(d *Dictionaries, issue *tracker.IndexedIssue) {
+	for _, v := range []struct {
+		kind string
+		key  string
+		name *string
+	}{
+		{tracker.DictQueue, issue.Queue, &issue.QueueName},
+		{tracker.DictStatus, issue.Status, &issue.StatusName},
+		{tracker.DictPriority, issue.Priority, &issue.PriorityName},
+		{tracker.DictType, issue.Type, &issue.TypeName},
+		{tracker.DictResolution, issue.Resolution, &issue.ResolutionName},
+	} {
+		if name := d.Name(v.kind, v.key); name != "" {
+			*v.name = name
+		}
+	}
+	if category := d.Category(issue.Status); category != "" {
+		issue.StatusCategory = category
+	}
+}
+
+// SetLanguage - sets the language of display names, e.g. ru or en; must be called before indexing
+func (idx *Indexer) SetLanguage(lang string) {
+	if lang == "" {
+		lang = defaultLanguage
+	}
+	idx.dictMu.Lock()
+	idx.lang = lang
+	idx.dictMu.Unlock()
+}
+
+// IndexDictionaries - replaces stored dictionaries and the in-memory cache.
+// Entries are replaced before removed ones are deleted, so that names stay available meanwhile
+func (idx *Indexer) IndexDictionaries(ctx context.Context, entries []tracker.DictionaryEntry) error {
+	current := make(map[int64]bool, len(entries))
+	if len(entries) > 0 {
+		docs := make([]document, 0, len(entries))
+		for _, e := range entries {
+			id := documentID(e.Kind + "|" + e.Key)
+			current[id] = true
+
+			var doc document
+			doc.int("id", id)
+			doc.str("kind", e.Kind)
+			doc.str("dict_key", e.Key)
+			doc.str("tracker_id", e.ID)
+			doc.json("names", e.Names)
+			doc.int("sort_order", int64(e.Order))
+			doc.str("category", e.Category)
+			doc.str("parent", e.Parent)
+			doc.str("status", e.Status)
+			doc.time("start_date", e.Start)
+			doc.time("end_date", e.End)
+			docs = append(docs, doc)
+		}
+		if _, err := idx.queryRows(ctx, replaceSQL(dictionariesTableName, docs)); err != nil {
+			return fmt.Errorf("replace dictionaries: %w", err)
+		}
+	}
+
+	if err := idx.deleteDictionaryEntries(ctx, current); err != nil {
+		return err
+	}
+
+	// issues are sorted by the order of their priority, which may have changed
+	for _, e := range entries {
+		if e.Kind != tracker.DictPriority {
+			continue
+		}
+		updateSQL := fmt.Sprintf(`UPDATE %s SET priority_order = %d WHERE priority = '%s'`,
+			tableName, e.Order, escapeSQL(e.Key))
+		if _, err := idx.queryRows(ctx, updateSQL); err != nil {
+			return fmt.Errorf("update priority order of %s: %w", e.Key, err)
+		}
+	}
+
+	idx.dictMu.Lock()
+	idx.dicts = newDictionaries(idx.language(), entries)
+	idx.dictMu.Unlock()
+	return nil
+}
+
+// deleteDictionaryEntries - deletes stored dictionary entries missing among the current ones
+func (idx *Indexer) deleteDictionaryEntries(ctx context.Context, current map[int64]bool) error {
+	var removed []string
+	for offset := 0; ; offset += scanPageSize {
+		rows, err := idx.queryRows(ctx, fmt.Sprintf(
+			`SELECT id FROM %s ORDER BY id ASC LIMIT %d, %d OPTION max_matches=%d`,
+			dictionariesTableName, offset, scanPageSize, offset+scanPageSize))
+		if err != nil {
+			return fmt.Errorf("scan dictionaries: %w", err)
+		}
+		for _, row := range rows {
+			if id := int64(getIntFromMap(row, "id")); !current[id] {
+				removed = append(removed, strconv.FormatInt(id, 10))
+			}
+		}
+		if len(rows) < scanPageSize {
+			break
+		}
+	}
+
+	for start := 0; start < len(removed); start += removeBatchSize {
+		end := min(start+removeBatchSize, len(removed))
+		deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE id IN (%s)`,
+			dictionariesTableName, strings.Join(removed[start:end], ", "))
+		if _, err := idx.queryRows(ctx, deleteSQL); err != nil {
+			return fmt.Errorf("delete removed dictionary entries: %w", err)
+		}
+	}
+	return nil
+}
+
+// language - returns the configured language; the caller holds dictMu
+func (idx *Indexer) language() string {
+	if idx.lang == "" {
+		return defaultLanguage
+	}
+	return idx.lang
+}
+
+// Dictionaries - returns cached dictionaries, loading them from the index on first use
+func (idx *Indexer) Dictionaries(ctx context.Context) (*Dictionaries, error) {
+	idx.dictMu.RLock()
+	dicts := idx.dicts
+	idx.dictMu.RUnlock()
+	if dicts != nil {
+		return dicts, nil
+	}
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT * FROM %s LIMIT 10000 OPTION max_matches=10000`, dictionariesTableName))
+	if err != nil {
+		return nil, fmt.Errorf("load dictionaries: %w", err)
+	}
+
+	entries := make([]tracker.DictionaryEntry, 0, len(rows))
+	for _, row := range rows {
+		e := tracker.DictionaryEntry{
+			Kind:     getStringFromMap(row, "kind"),
+			Key:      getStringFromMap(row, "dict_key"),
+			ID:       getStringFromMap(row, "tracker_id"),
+			Order:    getIntFromMap(row, "sort_order"),
+			Category: getStringFromMap(row, "category"),
+			Parent:   getStringFromMap(row, "parent"),
+			Status:   getStringFromMap(row, "status"),
+			Start:    getTimeFromMap(row, "start_date"),
+			End:      getTimeFromMap(row, "end_date"),
+		}
+		getJSONFromMap(row, "names", &e.Names)
+		entries = append(entries, e)
+	}
+
+	idx.dictMu.Lock()
+	defer idx.dictMu.Unlock()
+	if idx.dicts == nil {
+		idx.dicts = newDictionaries(idx.language(), entries)
+	}
+	return idx.dicts, nil
+}
@@ -0,0 +1,418 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"ytbs/tracker"
+)
+
+// FilterOp - comparison operator of a field filter
+type FilterOp string
+
+const (
+	// OpIn - field equals any of the values
+	OpIn FilterOp = ""
+	// OpNotIn - field equals none of the values
+	OpNotIn FilterOp = "not"
+	// OpEmpty - field has no value
+	OpEmpty FilterOp = "empty"
+	// OpNotEmpty - field has any value
+	OpNotEmpty FilterOp = "not_empty"
+)
+
+// FieldFilter - filter on a single field: list of values and an operator
+type FieldFilter struct {
+	Values []string `json:"values,omitempty"`
+	Op     FilterOp `json:"op,omitempty"`
+}
+
+// IsSet - checks if the filter restricts results
+func (f FieldFilter) IsSet() bool {
+	switch f.Op {
+	case OpEmpty, OpNotEmpty:
+		return true
+	default:
+		return len(f.Values) > 0
+	}
+}
+
+// Has - checks if the value is one of the filter values
+func (f FieldFilter) Has(value string) bool {
+	for _, v := range f.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// condition - builds SQL condition for a string column
+func (f FieldFilter) condition(column string) string {
+	switch f.Op {
+	case OpEmpty:
+		return fmt.Sprintf("%s = ''", column)
+	case OpNotEmpty:
+		return fmt.Sprintf("%s != ''", column)
+	}
+
+	if len(f.Values) == 0 {
+		return ""
+	}
+
+	quoted := make([]string, len(f.Values))
+	for i, v := range f.Values {
+		quoted[i] = "'" + escapeSQL(v) + "'"
+	}
+
+	if f.Op == OpNotIn {
+		return fmt.Sprintf("%s NOT IN (%s)", column, strings.Join(quoted, ", "))
+	}
+	return fmt.Sprintf("%s IN (%s)", column, strings.Join(quoted, ", "))
+}
+
+// LinkFilter - filter on links to other issues
+type LinkFilter struct {
+	// To - keys of linked issues
+	To []string `json:"to,omitempty"`
+	// Relations - link relations as seen from the filtered issue (e.g. blocks, depends_on)
+	Relations []string `json:"relations,omitempty"`
+	// OpenTargets - only links to issues without resolution
+	OpenTargets bool `json:"open_targets,omitempty"`
+}
+
+// IsSet - checks if the filter restricts results
+func (f LinkFilter) IsSet() bool {
+	return len(f.To) > 0 || len(f.Relations) > 0 || f.OpenTargets
+}
+
+// HistoryFilter - filter on past states of issues, resolved with the changelog
+type HistoryFilter struct {
+	// WasStatus - status names the issue had at some moment of the period
+	WasStatus []string `json:"was_status,omitempty"`
+	// Since, Until - period bounds of WasStatus, open if zero
+	Since time.Time `json:"since,omitempty"`
+	Until time.Time `json:"until,omitempty"`
+	// WasAssignee - names of current or previous assignees
+	WasAssignee []string `json:"was_assignee,omitempty"`
+}
+
+// IsSet - checks if the filter restricts results
+func (f HistoryFilter) IsSet() bool {
+	return len(f.WasStatus) > 0 || len(f.WasAssignee) > 0
+}
+
+// hasStatus - checks if the status is one of WasStatus
+func (f HistoryFilter) hasStatus(status string) bool {
+	for _, s := range f.WasStatus {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// dateLayout - format of date parameters
+const dateLayout = "2006-01-02"
+
+// parseDate - parses a date or RFC3339 time parameter; a date as the end of a period
+// covers the whole day. Invalid values are ignored
+func parseDate(value string, endOfDay bool) time.Time {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+	t, err := time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	if endOfDay {
+		return t.AddDate(0, 0, 1).Add(-time.Second)
+	}
+	return t
+}
+
+// filterFields - URL parameter names of SearchFilters fields and their table columns.
+// Fields with a dictionary are MVA columns of Tracker IDs, filtered by IDs or names
+var filterFields = []struct {
+	param  string
+	column string
+	field  func(f *SearchFilters) *FieldFilter
+	dict   string
+}{
+	{"queue", "queue", func(f *SearchFilters) *FieldFilter { return &f.Queue }, ""},
+	{"status", "status_name", func(f *SearchFilters) *FieldFilter { return &f.Status }, ""},
+	{"priority", "priority", func(f *SearchFilters) *FieldFilter { return &f.Priority }, ""},
+	{"author", "author_name", func(f *SearchFilters) *FieldFilter { return &f.Author }, ""},
+	{"assignee", "assignee_name", func(f *SearchFilters) *FieldFilter { return &f.Assignee }, ""},
+	{"status_category", "status_category", func(f *SearchFilters) *FieldFilter { return &f.StatusCategory }, ""},
+	{"component", "components", func(f *SearchFilters) *FieldFilter { return &f.Component }, tracker.DictComponent},
+	{"fix_version", "fix_versions", func(f *SearchFilters) *FieldFilter { return &f.FixVersion }, tracker.DictVersion},
+	{"affected_version", "affected_versions", func(f *SearchFilters) *FieldFilter { return &f.AffectedVersion }, tracker.DictVersion},
+	{"sprint", "sprints", func(f *SearchFilters) *FieldFilter { return &f.Sprint }, tracker.DictSprint},
+}
+
+// ParseSearchFilters - reads filters from URL parameters.
+// Each field accepts repeated values (queue=A&queue=B) and an operator (queue_op=not|empty|not_empty)
+func ParseSearchFilters(values url.Values) SearchFilters {
+	var filters SearchFilters
+
+	for _, ff := range filterFields {
+		field := ff.field(&filters)
+		field.Values = paramValues(values[ff.param])
+
+		switch op := FilterOp(values.Get(ff.param + "_op")); op {
+		case OpNotIn, OpEmpty, OpNotEmpty:
+			field.Op = op
+		}
+	}
+
+	filters.Links = LinkFilter{
+		To:          splitKeys(values["linked_to"]),
+		Relations:   paramValues(values["link_relation"]),
+		OpenTargets: values.Get("link_open") == "1",
+	}
+
+	filters.Within = splitKeys(values["within"])
+
+	filters.History = HistoryFilter{
+		WasStatus:   paramValues(values["was_status"]),
+		Since:       parseDate(values.Get("was_status_from"), false),
+		Until:       parseDate(values.Get("was_status_to"), true),
+		WasAssignee: paramValues(values["was_assignee"]),
+	}
+
+	filters.Checklist = ChecklistFilter{
+		Open:      values.Get("checklist_open") == "1",
+		Assignees: paramValues(values["checklist_assignee"]),
+		Overdue:   values.Get("checklist_overdue") == "1",
+	}
+
+	filters.CurrentSprint = paramValues(values["current_sprint"])
+	filters.Follower = paramValues(values["follower"])
+	filters.ResolvedFrom = parseDate(values.Get("resolved_from"), false)
+	filters.ResolvedTo = parseDate(values.Get("resolved_to"), true)
+
+	switch d := DeadlineFilter(values.Get("deadline")); d {
+	case DeadlineOverdue, DeadlineThisWeek, DeadlineNone:
+		filters.Deadline = d
+	}
+
+	filters.Custom = parseCustomFilters(values)
+
+	filters.GroupBy = values.Get("group_by")
+	if !IsGroupable(filters.GroupBy) {
+		filters.GroupBy = ""
+	}
+
+	if sort := values.Get("sort"); IsSortable(sort) {
+		filters.Sort = sort
+	}
+	filters.Boost = values.Get("boost") == "1"
+
+	return filters
+}
+
+// Query - encodes filters as URL parameters, reverse of ParseSearchFilters
+func (f SearchFilters) Query() url.Values {
+	values := url.Values{}
+
+	for _, ff := range filterFields {
+		field := ff.field(&f)
+		for _, v := range field.Values {
+			values.Add(ff.param, v)
+		}
+		if field.Op != OpIn {
+			values.Set(ff.param+"_op", string(field.Op))
+		}
+	}
+
+	for _, v := range f.Links.To {
+		values.Add("linked_to", v)
+	}
+	for _, v := range f.Links.Relations {
+		values.Add("link_relation", v)
+	}
+	if f.Links.OpenTargets {
+		values.Set("link_open", "1")
+	}
+
+	for _, v := range f.Within {
+		values.Add("within", v)
+	}
+
+	for _, v := range f.History.WasStatus {
+		values.Add("was_status", v)
+	}
+	if !f.History.Since.IsZero() {
+		values.Set("was_status_from", f.History.Since.Format(time.RFC3339))
+	}
+	if !f.History.Until.IsZero() {
+		values.Set("was_status_to", f.History.Until.Format(time.RFC3339))
+	}
+	for _, v := range f.History.WasAssignee {
+		values.Add("was_assignee", v)
+	}
+
+	if f.Checklist.Open {
+		values.Set("checklist_open", "1")
+	}
+	for _, v := range f.Checklist.Assignees {
+		values.Add("checklist_assignee", v)
+	}
+	if f.Checklist.Overdue {
+		values.Set("checklist_overdue", "1")
+	}
+
+	for _, v := range f.CurrentSprint {
+		values.Add("current_sprint", v)
+	}
+	for _, v := range f.Follower {
+		values.Add("follower", v)
+	}
+	if !f.ResolvedFrom.IsZero() {
+		values.Set("resolved_from", f.ResolvedFrom.Format(time.RFC3339))
+	}
+	if !f.ResolvedTo.IsZero() {
+		values.Set("resolved_to", f.ResolvedTo.Format(time.RFC3339))
+	}
+	if f.Deadline != "" {
+		values.Set("deadline", string(f.Deadline))
+	}
+
+	customQuery(f.Custom, values)
+
+	if f.GroupBy != "" {
+		values.Set("group_by", f.GroupBy)
+	}
+	if f.Sort != "" {
+		values.Set("sort", f.Sort)
+	}
+	if f.Boost {
+		values.Set("boost", "1")
+	}
+
+	return values
+}
+
+// IsEmpty - checks if no filter is set
+func (f SearchFilters) IsEmpty() bool {
+	if f.Links.IsSet() || len(f.Within) > 0 || f.History.IsSet() || f.Checklist.IsSet() || f.Deadline != "" ||
+		len(f.CurrentSprint) > 0 || len(f.Follower) > 0 || !f.ResolvedFrom.IsZero() || !f.ResolvedTo.IsZero() {
+		return false
+	}
+	for _, cf := range f.Custom {
+		if cf.IsSet() {
+			return false
+		}
+	}
+	for _, ff := range filterFields {
+		if ff.field(&f).IsSet() {
+			return false
+		}
+	}
+	return true
+}
+
+// conditions - builds SQL conditions for all set filters.
+// Filters on related tables are resolved to issue keys with additional queries
+func (idx *Indexer) conditions(ctx context.Context, f SearchFilters) ([]string, error) {
+	var conditions []string
+	for _, ff := range filterFields {
+		field := ff.field(&f)
+		if ff.dict == "" {
+			if cond := field.condition(ff.column); cond != "" {
+				conditions = append(conditions, cond)
+			}
+			continue
+		}
+		if field.IsSet() {
+			dicts, err := idx.Dictionaries(ctx)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, refsCondition(ff.column, field.Op, dicts.resolve(ff.dict, field.Values)))
+		}
+	}
+
+	if len(f.CurrentSprint) > 0 {
+		dicts, err := idx.Dictionaries(ctx)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, refsCondition("sprints", OpIn, dicts.currentSprints(f.CurrentSprint)))
+	}
+
+	if f.Deadline != "" {
+		conditions = append(conditions, f.Deadline.condition(time.Now()))
+	}
+
+	if len(f.Follower) > 0 {
+		conditions = append(conditions, userCondition("follower_ids", f.Follower))
+	}
+
+	if !f.ResolvedFrom.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("resolved_at >= %d", f.ResolvedFrom.Unix()))
+	}
+	if !f.ResolvedTo.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("resolved_at > 0 AND resolved_at <= %d", f.ResolvedTo.Unix()))
+	}
+
+	conditions = append(conditions, customConditions(f.Custom)...)
+
+	if len(f.Within) > 0 {
+		conditions = append(conditions, withinCondition(f.Within))
+	}
+
+	if f.History.IsSet() {
+		history, err := idx.historyConditions(ctx, f.History)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, history...)
+	}
+
+	if f.Links.IsSet() {
+		cond, err := idx.linkCondition(ctx, f.Links)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+	}
+
+	if f.Checklist.IsSet() {
+		cond, err := idx.checklistCondition(ctx, f.Checklist)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+	}
+
+	return conditions, nil
+}
+
+// paramValues - collects repeated parameter values as given, without empty ones
+func paramValues(params []string) []string {
+	var values []string
+	for _, v := range params {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// splitKeys - collects repeated parameter values of issue keys, each of which may hold
+// several keys separated by commas or spaces, as keys never contain them
+func splitKeys(params []string) []string {
+	var keys []string
+	for _, v := range params {
+		keys = append(keys, strings.FieldsFunc(v, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})...)
+	}
+	return keys
+}
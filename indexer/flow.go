@@ -0,0 +1,323 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"time"
+
+	"ytbs/tracker"
+)
+
+const (
+	// maxFlowIssues - upper bound of resolved issues measured by a flow report
+	maxFlowIssues = 10000
+	// flowKeysBatch - number of issue keys per changelog query
+	flowKeysBatch = 500
+)
+
+// flowDimensions - dimensions of the flow report and their table columns
+var flowDimensions = map[string]string{
+	"queue":  "queue",
+	"type":   "type",
+	"period": "resolved_at",
+}
+
+// flowPeriods - lengths of report periods
+var flowPeriods = map[string]bool{
+	"week":  true,
+	"month": true,
+}
+
+// IsFlowDimension - checks if the flow report can be grouped by the dimension
+func IsFlowDimension(by string) bool {
+	_, ok := flowDimensions[by]
+	return ok
+}
+
+// FlowOptions - flow report parameters
+type FlowOptions struct {
+	// By - report dimension: queue, type or period of resolution
+	By string
+	// Period - length of periods when grouped by period: week or month
+	Period string
+}
+
+// ParseFlowOptions - reads report parameters by and period; the report is grouped by queue
+// and periods are months by default
+func ParseFlowOptions(values url.Values) FlowOptions {
+	opts := FlowOptions{
+		By:     values.Get("by"),
+		Period: values.Get("period"),
+	}
+	if opts.By == "" {
+		opts.By = "queue"
+	}
+	if !flowPeriods[opts.Period] {
+		opts.Period = "month"
+	}
+	return opts
+}
+
+// Query - converts report parameters back to URL query parameters
+func (o FlowOptions) Query() url.Values {
+	values := url.Values{}
+	values.Set("by", o.By)
+	if o.By == "period" {
+		values.Set("period", o.Period)
+	}
+	return values
+}
+
+// Percentiles - distribution of durations in days
+type Percentiles struct {
+	// Count - number of measured issues
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P75   float64 `json:"p75"`
+	P85   float64 `json:"p85"`
+	P95   float64 `json:"p95"`
+}
+
+// FlowRow - lead and cycle time of issues of one dimension value
+type FlowRow struct {
+	Value string `json:"value"`
+	// Label - display name of the value, e.g. queue name
+	Label string `json:"label,omitempty"`
+	// LeadTime - from creation to resolution
+	LeadTime Percentiles `json:"lead_time"`
+	// CycleTime - time spent in statuses of the in progress category, issues never in progress are left out
+	CycleTime Percentiles `json:"cycle_time"`
+}
+
+// FlowReport - lead and cycle time of resolved issues grouped by a dimension
+type FlowReport struct {
+	By     string `json:"by"`
+	Period string `json:"period,omitempty"`
+	// Total - all measured issues together
+	Total FlowRow   `json:"total"`
+	Rows  []FlowRow `json:"rows"`
+	// Truncated - more issues matched than were measured
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// flowIssue - resolved issue being measured
+type flowIssue struct {
+	key        string
+	group      string
+	label      string
+	status     string
+	createdAt  time.Time
+	resolvedAt time.Time
+	inProgress time.Duration
+}
+
+// FlowReport - measures lead and cycle time of resolved issues matching the query and filters
+func (idx *Indexer) FlowReport(ctx context.Context, query string, filters SearchFilters, opts FlowOptions) (*FlowReport, error) {
+	if !IsFlowDimension(opts.By) {
+		return nil, fmt.Errorf("unsupported report dimension %q", opts.By)
+	}
+
+	report := &FlowReport{By: opts.By, Rows: []FlowRow{}}
+	if opts.By == "period" {
+		report.Period = opts.Period
+	}
+
+	whereClause, err := idx.buildWhere(ctx, query, filters)
+	if err != nil {
+		return nil, err
+	}
+	if whereClause == "" {
+		whereClause = "WHERE resolved_at > 0"
+	} else {
+		whereClause += " AND resolved_at > 0"
+	}
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT issue_key, queue, queue_name, type, type_name, status, created_at, resolved_at
+		 FROM %s %s ORDER BY resolved_at DESC LIMIT %d OPTION max_matches=%d`,
+		tableName, whereClause, maxFlowIssues+1, maxFlowIssues+1))
+	if err != nil {
+		return nil, fmt.Errorf("flow issues: %w", err)
+	}
+	if len(rows) > maxFlowIssues {
+		rows = rows[:maxFlowIssues]
+		report.Truncated = true
+	}
+
+	issues := make([]*flowIssue, 0, len(rows))
+	for _, row := range rows {
+		issue := &flowIssue{
+			key:        getStringFromMap(row, "issue_key"),
+			status:     getStringFromMap(row, "status"),
+			createdAt:  getTimeFromMap(row, "created_at"),
+			resolvedAt: getTimeFromMap(row, "resolved_at"),
+		}
+		switch opts.By {
+		case "queue":
+			issue.group, issue.label = getStringFromMap(row, "queue"), getStringFromMap(row, "queue_name")
+		case "type":
+			issue.group, issue.label = getStringFromMap(row, "type"), getStringFromMap(row, "type_name")
+		case "period":
+			issue.group = periodStart(issue.resolvedAt, opts.Period)
+		}
+		issues = append(issues, issue)
+	}
+
+	if err := idx.measureInProgress(ctx, issues); err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]*flowIssue)
+	for _, issue := range issues {
+		groups[issue.group] = append(groups[issue.group], issue)
+	}
+	for value, group := range groups {
+		row := flowRow(group)
+		row.Value, row.Label = value, group[0].label
+		if row.Label == value {
+			row.Label = ""
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	report.Total = flowRow(issues)
+
+	if opts.By == "period" {
+		sort.Slice(report.Rows, func(i, j int) bool { return report.Rows[i].Value < report.Rows[j].Value })
+	} else {
+		sort.Slice(report.Rows, func(i, j int) bool {
+			if report.Rows[i].LeadTime.Count != report.Rows[j].LeadTime.Count {
+				return report.Rows[i].LeadTime.Count > report.Rows[j].LeadTime.Count
+			}
+			return report.Rows[i].Value < report.Rows[j].Value
+		})
+	}
+
+	return report, nil
+}
+
+// measureInProgress - sums time the issues spent in statuses of the in progress category
+// before their resolution, replaying status transitions of the changelog
+func (idx *Indexer) measureInProgress(ctx context.Context, issues []*flowIssue) error {
+	dicts, err := idx.Dictionaries(ctx)
+	if err != nil {
+		return err
+	}
+	inProgress := func(status string) bool {
+		return dicts.Category(status) == tracker.CategoryInProgress
+	}
+
+	byKey := make(map[string]*flowIssue, len(issues))
+	keys := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		byKey[issue.key] = issue
+		keys = append(keys, issue.key)
+	}
+
+	transitions := make(map[string][]changeRow, len(issues))
+	for start := 0; start < len(keys); start += flowKeysBatch {
+		end := min(start+flowKeysBatch, len(keys))
+		rows, err := idx.queryRows(ctx, fmt.Sprintf(
+			`SELECT issue_key, from_value, to_value, changed_at FROM %s
+			 WHERE field = '%s' AND %s ORDER BY changed_at ASC LIMIT %d OPTION max_matches=%d`,
+			changesTableName, tracker.FieldStatus, keysCondition(keys[start:end]), maxHistoryRows, maxHistoryRows))
+		if err != nil {
+			return fmt.Errorf("status transitions: %w", err)
+		}
+		for _, row := range rows {
+			key := getStringFromMap(row, "issue_key")
+			transitions[key] = append(transitions[key], changeRow{
+				key:       key,
+				from:      getStringFromMap(row, "from_value"),
+				to:        getStringFromMap(row, "to_value"),
+				changedAt: getTimeFromMap(row, "changed_at"),
+			})
+		}
+	}
+
+	for key, issue := range byKey {
+		changes := transitions[key]
+
+		// the issue is created in the status it first moved from, or has never left the current one
+		status, since := issue.status, issue.createdAt
+		if len(changes) > 0 {
+			status = changes[0].from
+		}
+		for _, c := range changes {
+			if inProgress(status) {
+				issue.inProgress += clippedDuration(since, c.changedAt, issue.resolvedAt)
+			}
+			status, since = c.to, c.changedAt
+		}
+		if inProgress(status) {
+			issue.inProgress += clippedDuration(since, issue.resolvedAt, issue.resolvedAt)
+		}
+	}
+	return nil
+}
+
+// clippedDuration - length of the interval up to the limit, zero if it starts after the limit
+func clippedDuration(from, to, limit time.Time) time.Duration {
+	if to.After(limit) {
+		to = limit
+	}
+	if !to.After(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
+
+// flowRow - computes lead and cycle time distributions of the issues
+func flowRow(issues []*flowIssue) FlowRow {
+	var lead, cycle []float64
+	for _, issue := range issues {
+		lead = append(lead, days(clippedDuration(issue.createdAt, issue.resolvedAt, issue.resolvedAt)))
+		if issue.inProgress > 0 {
+			cycle = append(cycle, days(issue.inProgress))
+		}
+	}
+	return FlowRow{LeadTime: percentiles(lead), CycleTime: percentiles(cycle)}
+}
+
+// percentiles - distribution of the values by the nearest-rank method
+func percentiles(values []float64) Percentiles {
+	p := Percentiles{Count: len(values)}
+	if len(values) == 0 {
+		return p
+	}
+	sort.Float64s(values)
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	rank := func(q float64) float64 {
+		i := int(math.Ceil(q*float64(len(values)))) - 1
+		return values[max(i, 0)]
+	}
+	p.Mean = roundDays(sum / float64(len(values)))
+	p.P50, p.P75, p.P85, p.P95 = rank(0.5), rank(0.75), rank(0.85), rank(0.95)
+	return p
+}
+
+// days - converts a duration to days rounded to tenths
+func days(d time.Duration) float64 {
+	return roundDays(d.Hours() / 24)
+}
+
+// roundDays - rounds days to tenths
+func roundDays(days float64) float64 {
+	return math.Round(days*10) / 10
+}
+
+// periodStart - returns the first day of the week or month of the time as YYYY-MM-DD
+func periodStart(t time.Time, period string) string {
+	if period == "week" {
+		return weekStart(t).Format(dateLayout)
+	}
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local).Format(dateLayout)
+}
@@ -0,0 +1,95 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+)
+
+// defaultGroupSize - number of top results shown per group
+const defaultGroupSize = 3
+
+// groupColumns - fields available for grouping and their table columns
+var groupColumns = map[string]string{
+	"queue":    "queue",
+	"assignee": "assignee_name",
+	"status":   "status_name",
+}
+
+// ResultGroup - group of search results sharing the same field value
+type ResultGroup struct {
+	Value   string         `json:"value"`
+	Total   int            `json:"total"`
+	Results []SearchResult `json:"results"`
+}
+
+// Rest - number of group matches not included in Results
+func (g ResultGroup) Rest() int {
+	if rest := g.Total - len(g.Results); rest > 0 {
+		return rest
+	}
+	return 0
+}
+
+// IsGroupable - checks if results can be grouped by the field
+func IsGroupable(field string) bool {
+	_, ok := groupColumns[field]
+	return ok
+}
+
+// searchGrouped - returns a range of top results of each group ordered by group size
+func (idx *Indexer) searchGrouped(ctx context.Context, whereClause, groupBy string, offset, limit int) ([]SearchResult, error) {
+	column, ok := groupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported group field %q", groupBy)
+	}
+
+	searchSQL := fmt.Sprintf(
+		`SELECT id, issue_key, url, summary, status_name, assignee_name, queue, priority, priority_name, deadline, status_category,
+		        COUNT(*) as group_total,
+		        %s as highlight
+		 FROM %s
+		 %s
+		 GROUP %d BY %s
+		 WITHIN GROUP ORDER BY updated_at DESC
+		 ORDER BY group_total DESC
+		 LIMIT %d, %d`,
+		highlightExpr, tableName, whereClause, defaultGroupSize, column, offset, limit)
+
+	rows, err := idx.queryRows(ctx, searchSQL)
+	if err != nil {
+		return nil, fmt.Errorf("grouped search: %w", err)
+	}
+
+	var results []SearchResult
+	for _, rowMap := range rows {
+		result := extractRow(rowMap)
+		result.Queue = getStringFromMap(rowMap, "queue")
+		result.Priority = getStringFromMap(rowMap, "priority")
+		result.PriorityName = getStringFromMap(rowMap, "priority_name")
+		result.Deadline = getTimeFromMap(rowMap, "deadline")
+		result.StatusCategory = getStringFromMap(rowMap, "status_category")
+		result.Group = getStringFromMap(rowMap, column)
+		result.GroupTotal = getIntFromMap(rowMap, "group_total")
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// GroupResults - collects grouped search results into groups preserving their order
+func GroupResults(results []SearchResult) []ResultGroup {
+	var groups []ResultGroup
+	positions := make(map[string]int)
+
+	for _, r := range results {
+		pos, ok := positions[r.Group]
+		if !ok {
+			pos = len(groups)
+			positions[r.Group] = pos
+			groups = append(groups, ResultGroup{Value: r.Group, Total: r.GroupTotal})
+		}
+		groups[pos].Results = append(groups[pos].Results, r)
+	}
+
+	return groups
+}
@@ -0,0 +1,76 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ancestorSeparator - separator of keys in the stored ancestor path
+const ancestorSeparator = "/"
+
+// hashKeys - converts issue keys to numeric values for MVA
+func hashKeys(keys []string) []int64 {
+	result := make([]int64, len(keys))
+	for i, key := range keys {
+		result[i] = hashString(key)
+	}
+	return result
+}
+
+// splitAncestorPath - parses the stored ancestor path
+func splitAncestorPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ancestorSeparator)
+}
+
+// withinCondition - matches descendants of any of the issues
+func withinCondition(keys []string) string {
+	hashes := hashKeys(keys)
+	values := make([]string, len(hashes))
+	for i, h := range hashes {
+		values[i] = fmt.Sprintf("%d", h)
+	}
+	return fmt.Sprintf("ANY(ancestors) IN (%s)", strings.Join(values, ", "))
+}
+
+// Rollup - summary of all descendants of an issue
+type Rollup struct {
+	Total    int          `json:"total"`
+	Open     int          `json:"open"`
+	Statuses []FacetValue `json:"statuses"`
+}
+
+// Done - number of resolved descendants
+func (r Rollup) Done() int {
+	return r.Total - r.Open
+}
+
+// GetRollup - counts descendants of the issue per status
+func (idx *Indexer) GetRollup(ctx context.Context, key string) (*Rollup, error) {
+	key, err := idx.resolveKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	whereClause := "WHERE " + withinCondition([]string{key})
+
+	var rollup Rollup
+	if rollup.Total, err = idx.count(ctx, whereClause); err != nil {
+		return nil, err
+	}
+	if rollup.Total == 0 {
+		return &rollup, nil
+	}
+
+	if rollup.Open, err = idx.count(ctx, whereClause+" AND resolution = ''"); err != nil {
+		return nil, err
+	}
+
+	if rollup.Statuses, err = idx.facet(ctx, whereClause, "status"); err != nil {
+		return nil, fmt.Errorf("rollup of %s: %w", key, err)
+	}
+
+	return &rollup, nil
+}
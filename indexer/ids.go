@@ -0,0 +1,361 @@
+package indexer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ytbs/tracker"
+)
+
+const (
+	// maxDocumentID - upper bound of generated document IDs. Manticore returns IDs as JSON numbers,
+	// so IDs stay within the float64 mantissa to be read back exactly
+	maxDocumentID = 1<<53 - 1
+	// maxIDProbes - attempts to find a free document ID before giving up
+	maxIDProbes = 16
+	// scanPageSize - documents read per query while scanning the index
+	scanPageSize = 1000
+)
+
+// documentID - derives a document ID from a stable identifier with SHA-256,
+// so that different identifiers practically never share an ID
+func documentID(s string) int64 {
+	sum := sha256.Sum256([]byte(s))
+	id := int64(binary.BigEndian.Uint64(sum[:8]) & maxDocumentID)
+	if id == 0 {
+		return 1
+	}
+	return id
+}
+
+// exactID - checks if the ID read from Manticore is exact rather than rounded by JSON decoding
+func exactID(id int64) bool {
+	return id > 0 && id <= maxDocumentID
+}
+
+// legacyID - checks if the stored ID is the old hash of one of the keys the issue was indexed under.
+// Such IDs may collide and are replaced. Large ones are rounded when read back, so they are compared
+// with the same rounding
+func legacyID(id int64, keys []string) bool {
+	for _, key := range keys {
+		h := hashString(key)
+		if id == h || float64(id) == float64(h) {
+			return true
+		}
+	}
+	return false
+}
+
+// reusable - checks if the stored ID of the issue may be kept
+func (s storedKeys) reusable() bool {
+	return exactID(s.id) && !legacyID(s.id, append([]string{s.key}, s.previousKeys...))
+}
+
+// idSeed - stable identifier of an issue the document ID is derived from.
+// Tracker IDs survive moves between queues, unlike keys
+func idSeed(issue tracker.IndexedIssue) string {
+	if issue.ID != "" {
+		return "issue|" + issue.ID
+	}
+	return "key|" + issue.Key
+}
+
+// issueID - document ID of an issue. Numeric Tracker IDs are used as is. Otherwise the ID
+// the issue is indexed with is kept unless it is a legacy one, and a new one is derived
+// from the Tracker ID, probing further IDs while they are taken by other issues
+func (idx *Indexer) issueID(ctx context.Context, issue tracker.IndexedIssue, stored []storedKeys) (int64, error) {
+	if id, err := strconv.ParseInt(issue.ID, 10, 64); err == nil {
+		return id, nil
+	}
+
+	for _, s := range stored {
+		if s.key == issue.Key && s.reusable() {
+			return s.id, nil
+		}
+	}
+	for _, s := range stored {
+		if s.reusable() {
+			return s.id, nil
+		}
+	}
+
+	keys := append([]string{issue.Key}, issue.PreviousKeys...)
+	seed := idSeed(issue)
+	for probe := 0; probe < maxIDProbes; probe++ {
+		id := documentID(seed)
+		if probe > 0 {
+			id = documentID(fmt.Sprintf("%s#%d", seed, probe))
+		}
+
+		owner, err := idx.documentOwner(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		if owner == "" || containsKey(keys, owner) {
+			return id, nil
+		}
+		log.Printf("Warning: document ID %d of %s is taken by %s, probing the next one", id, issue.Key, owner)
+	}
+	return 0, fmt.Errorf("no free document ID for %s after %d probes", issue.Key, maxIDProbes)
+}
+
+// documentOwner - returns the key of the issue stored with the ID, empty if the ID is free
+func (idx *Indexer) documentOwner(ctx context.Context, id int64) (string, error) {
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(`SELECT issue_key FROM %s WHERE id = %d`, tableName, id))
+	if err != nil {
+		return "", fmt.Errorf("document %d: %w", id, err)
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+	return getStringFromMap(rows[0], "issue_key"), nil
+}
+
+// containsKey - checks if the key is among the keys
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// IDReport - result of the document ID verification
+type IDReport struct {
+	// Documents - number of scanned issue documents
+	Documents int `json:"documents"`
+	// Duplicates - keys of issues stored in several documents
+	Duplicates []string `json:"duplicates,omitempty"`
+	// Legacy - keys of issues with IDs of the old key hash, which may collide
+	Legacy []string `json:"legacy,omitempty"`
+	// Repaired - keys of issues moved to new document IDs
+	Repaired []string `json:"repaired,omitempty"`
+}
+
+// OK - checks if no problems were found
+func (r IDReport) OK() bool {
+	return len(r.Duplicates) == 0 && len(r.Legacy) == 0
+}
+
+// storedDocument - identity of a scanned issue document
+type storedDocument struct {
+	storedKeys
+	trackerID string
+	syncedAt  time.Time
+}
+
+// VerifyIDs - scans issue documents for issues stored more than once and for IDs
+// of the old key hash, which silently overwrite issues when they collide.
+// With repair set, each affected issue is kept in a single document with a collision-free ID
+func (idx *Indexer) VerifyIDs(ctx context.Context, repair bool) (*IDReport, error) {
+	byKey := make(map[string][]storedDocument)
+	byTrackerID := make(map[string]map[string]bool)
+
+	report := &IDReport{}
+	for offset := 0; ; offset += scanPageSize {
+		rows, err := idx.queryRows(ctx, fmt.Sprintf(
+			`SELECT id, issue_key, previous_keys, tracker_id, synced_at FROM %s ORDER BY issue_key ASC LIMIT %d, %d OPTION max_matches=%d`,
+			tableName, offset, scanPageSize, offset+scanPageSize))
+		if err != nil {
+			return nil, fmt.Errorf("scan documents: %w", err)
+		}
+		for _, row := range rows {
+			d := storedDocument{
+				storedKeys: storedKeys{
+					id:  int64(getIntFromMap(row, "id")),
+					key: getStringFromMap(row, "issue_key"),
+				},
+				trackerID: getStringFromMap(row, "tracker_id"),
+				syncedAt:  getTimeFromMap(row, "synced_at"),
+			}
+			getJSONFromMap(row, "previous_keys", &d.previousKeys)
+			byKey[d.key] = append(byKey[d.key], d)
+			if d.trackerID != "" {
+				if byTrackerID[d.trackerID] == nil {
+					byTrackerID[d.trackerID] = make(map[string]bool)
+				}
+				byTrackerID[d.trackerID][d.key] = true
+			}
+			report.Documents++
+		}
+		if len(rows) < scanPageSize {
+			break
+		}
+	}
+
+	affected := make(map[string]bool)
+	for key, docs := range byKey {
+		if len(docs) > 1 {
+			report.Duplicates = append(report.Duplicates, key)
+			affected[key] = true
+			continue
+		}
+		d := docs[0]
+		if _, err := strconv.ParseInt(d.trackerID, 10, 64); err != nil && !d.reusable() {
+			report.Legacy = append(report.Legacy, key)
+			affected[key] = true
+		}
+	}
+	// documents of one Tracker issue under different keys are left from moves
+	for _, keys := range byTrackerID {
+		if len(keys) < 2 {
+			continue
+		}
+		for key := range keys {
+			if !affected[key] {
+				report.Duplicates = append(report.Duplicates, key)
+				affected[key] = true
+			}
+		}
+	}
+	sort.Strings(report.Duplicates)
+	sort.Strings(report.Legacy)
+
+	if !repair {
+		return report, nil
+	}
+
+	keys := make([]string, 0, len(affected))
+	for key := range affected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := idx.repairDocument(ctx, key); err != nil {
+			return report, err
+		}
+		report.Repaired = append(report.Repaired, key)
+	}
+	return report, nil
+}
+
+// repairDocument - stores the latest synced document of the issue under a collision-free ID
+// and removes its other documents
+func (idx *Indexer) repairDocument(ctx context.Context, key string) error {
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT * FROM %s WHERE issue_key = '%s' ORDER BY synced_at DESC LIMIT 1`, tableName, escapeSQL(key)))
+	if err != nil {
+		return fmt.Errorf("read document of %s: %w", key, err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	row := rows[0]
+
+	issue := tracker.IndexedIssue{ID: getStringFromMap(row, "tracker_id"), Key: key}
+	getJSONFromMap(row, "previous_keys", &issue.PreviousKeys)
+
+	// stored IDs are not reused, so that a free one is probed
+	id, err := idx.issueID(ctx, issue, nil)
+	if err != nil {
+		return err
+	}
+
+	doc := rowDocument(issueColumns(), row)
+	doc.set(issueMultiValues(row))
+	doc.int("id", id)
+	if _, err := idx.queryRows(ctx, replaceSQL(tableName, []document{doc})); err != nil {
+		return fmt.Errorf("replace document of %s: %w", key, err)
+	}
+
+	deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE issue_key = '%s' AND id != %d`, tableName, escapeSQL(key), id)
+	if _, err := idx.queryRows(ctx, deleteSQL); err != nil {
+		return fmt.Errorf("delete old documents of %s: %w", key, err)
+	}
+	if issue.ID != "" {
+		deleteSQL = fmt.Sprintf(`DELETE FROM %s WHERE tracker_id = '%s' AND id != %d`, tableName, escapeSQL(issue.ID), id)
+		if _, err := idx.queryRows(ctx, deleteSQL); err != nil {
+			return fmt.Errorf("delete moved documents of %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// issueColumns - columns of the issues table including declared custom fields
+func issueColumns() []column {
+	for _, t := range tables {
+		if t.name == tableName {
+			return append(append([]column{}, t.columns...), customColumns()...)
+		}
+	}
+	return nil
+}
+
+// issueMultiValues - rebuilds multi-value attributes of a stored issue from the columns they are
+// derived from, since values above 2^53 are rounded when read back
+func issueMultiValues(row map[string]interface{}) document {
+	var planning issuePlanning
+	getJSONFromMap(row, "planning", &planning)
+	var followers []tracker.IndexedUser
+	getJSONFromMap(row, "followers", &followers)
+	var previousKeys []string
+	getJSONFromMap(row, "previous_keys", &previousKeys)
+
+	var doc document
+	doc.multi("ancestors", hashKeys(splitAncestorPath(getStringFromMap(row, "ancestor_path"))))
+	doc.multi("components", refIDs(planning.Components))
+	doc.multi("fix_versions", refIDs(planning.FixVersions))
+	doc.multi("affected_versions", refIDs(planning.AffectedVersions))
+	doc.multi("sprints", refIDs(planning.Sprints))
+	doc.multi("follower_ids", userIDs(followers))
+	doc.multi("key_ids", keyIDs(append([]string{getStringFromMap(row, "issue_key")}, previousKeys...)))
+	return doc
+}
+
+// rowDocument - converts a row read with SELECT * back to a document of the columns.
+// Multi-value attributes are exact only for values up to 2^53
+func rowDocument(columns []column, row map[string]interface{}) document {
+	var doc document
+	for _, c := range columns {
+		switch def := strings.Fields(c.def)[0]; def {
+		case "INTEGER", "BIGINT", "TIMESTAMP":
+			n, _ := strconv.ParseInt(getStringFromMap(row, c.name), 10, 64)
+			doc.int(c.name, n)
+		case "FLOAT":
+			f, _ := strconv.ParseFloat(getStringFromMap(row, c.name), 64)
+			doc.float(c.name, f)
+		case "MULTI", "MULTI64":
+			doc.multi(c.name, rowValues(row[c.name]))
+		case "JSON":
+			var raw json.RawMessage
+			getJSONFromMap(row, c.name, &raw)
+			if len(raw) == 0 {
+				raw = json.RawMessage("null")
+			}
+			doc.json(c.name, raw)
+		default:
+			doc.str(c.name, getStringFromMap(row, c.name))
+		}
+	}
+	return doc
+}
+
+// rowValues - parses a multi-value attribute returned as a comma separated string or a list
+func rowValues(value any) []int64 {
+	var parts []string
+	switch v := value.(type) {
+	case string:
+		parts = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			parts = append(parts, getStringFromMap(map[string]interface{}{"v": item}, "v"))
+		}
+	}
+
+	var values []int64
+	for _, p := range parts {
+		if n, err := strconv.ParseInt(strings.TrimSpace(p), 10, 64); err == nil {
+			values = append(values, n)
+		}
+	}
+	return values
+}
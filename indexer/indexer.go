@@ -2,21 +2,42 @@ package indexer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"ytbs/tracker"
 
 	Manticoresearch "github.com/manticoresoftware/manticoresearch-go"
 )
 
-const tableName = "issues"
+const (
+	tableName             = "issues"
+	commentsTableName     = "comments"
+	linksTableName        = "issue_links"
+	changesTableName      = "issue_changes"
+	attachmentsTableName  = "attachments"
+	worklogTableName      = "worklog"
+	checklistTableName    = "checklist_items"
+	dictionariesTableName = "dictionaries"
+	summonsTableName      = "summons"
+	checkpointTableName   = "sync_checkpoint"
+)
+
+// highlightExpr - snippet of matched text fields with matches in <b> tags
+const highlightExpr = `HIGHLIGHT({before_match='<b>', after_match='</b>'}, 'summary,description,comments_text,code_text,attachment_names,worklog_text,checklist_text')`
 
 // Indexer - index for Manticoresearch
 type Indexer struct {
 	client *Manticoresearch.APIClient
+
+	dictMu sync.RWMutex
+	dicts  *Dictionaries
+	lang   string
 }
 
 // NewIndexer - creates a new Indexer instance
@@ -29,113 +50,175 @@ func NewIndexer(manticoreURL string) *Indexer {
 	}
 }
 
-// CreateTable - creates the issues table if it doesn't exist
+// CreateTable - creates the index tables if they don't exist
 func (idx *Indexer) CreateTable(ctx context.Context) error {
-	// Manticore CREATE TABLE syntax
-	// TEXT - full-text search
-	// STRING - exact match, filtering
-	// BIGINT - numbers
-	// TIMESTAMP - dates
-	// MULTI - arrays for MVA (multi-value attributes)
-	createSQL := `CREATE TABLE IF NOT EXISTS ` + tableName + ` (
-		id BIGINT,
-		issue_key STRING,
-		url STRING,
-		summary TEXT,
-		description TEXT,
-		comments_text TEXT,
-		queue STRING,
-		status STRING,
-		status_name STRING,
-		priority STRING,
-		type STRING,
-		resolution STRING,
-		author STRING,
-		author_name STRING,
-		assignee STRING,
-		assignee_name STRING,
-		tags MULTI,
-		created_at TIMESTAMP,
-		updated_at TIMESTAMP
-	) morphology='stem_en, stem_ru' html_strip='1'`
-
-	req := idx.client.UtilsAPI.Sql(ctx).Body(createSQL)
-	_, _, err := req.Execute()
-	if err != nil {
-		return fmt.Errorf("create table: %w", err)
+	for _, t := range tables {
+		if t.name == tableName {
+			// columns of declared custom fields are added to existing tables as well
+			t.columns = append(t.columns, customColumns()...)
+		}
+		if err := idx.createTable(ctx, t); err != nil {
+			return err
+		}
 	}
-
-	log.Printf("Table '%s' created/verified", tableName)
 	return nil
 }
 
-// IndexIssues - indexes a batch of issues
-func (idx *Indexer) IndexIssues(ctx context.Context, issues []tracker.IndexedIssue) error {
-	if len(issues) == 0 {
-		return nil
+// indexBatch - indexes a batch of issues
+func (idx *Indexer) indexBatch(ctx context.Context, issues []tracker.IndexedIssue) error {
+	dicts, err := idx.Dictionaries(ctx)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("Indexing %d issues...", len(issues))
+	for _, issue := range issues {
+		applyDictionaries(dicts, &issue)
 
-	batchSize := 100
-	for i := 0; i < len(issues); i += batchSize {
-		end := i + batchSize
-		if end > len(issues) {
-			end = len(issues)
+		// the issue may be indexed under the keys it had before a move
+		stored, err := idx.findByKeys(ctx, append([]string{issue.Key}, issue.PreviousKeys...))
+		if err != nil {
+			return err
 		}
+		mergeKeys(&issue, stored)
 
-		batch := issues[i:end]
-		if err := idx.indexBatch(ctx, batch); err != nil {
-			return fmt.Errorf("index batch %d-%d: %w", i, end, err)
+		// Manticore requires numeric IDs
+		id, err := idx.issueID(ctx, issue, stored)
+		if err != nil {
+			return err
 		}
 
-		log.Printf("Indexed %d/%d issues", end, len(issues))
-	}
-
-	return nil
-}
-
-// indexBatch - indexes a batch of issues
-func (idx *Indexer) indexBatch(ctx context.Context, issues []tracker.IndexedIssue) error {
-	for _, issue := range issues {
-		// Manticore requires numeric IDs
-		id, err := strconv.ParseInt(issue.ID, 10, 64)
+		statusSince, err := idx.statusSince(ctx, issue)
 		if err != nil {
-			// fallback: hash the issue key to get a numeric ID
-			id = hashString(issue.Key)
+			return err
 		}
 
-		// TODO: why api fails as 409 and only SQL way works?
-		sql := fmt.Sprintf(`REPLACE INTO %s (id, issue_key, url, summary, description, comments_text, 
-			queue, status, status_name, priority, type, resolution, 
-			author, author_name, assignee, assignee_name, created_at, updated_at) 
-			VALUES (%d, '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', %d, %d)`,
-			tableName,
-			id,
-			escapeSQL(issue.Key),
-			escapeSQL(issue.URL),
-			escapeSQL(issue.Summary),
-			escapeSQL(issue.Description),
-			escapeSQL(issue.CommentsText),
-			escapeSQL(issue.Queue),
-			escapeSQL(issue.Status),
-			escapeSQL(issue.StatusName),
-			escapeSQL(issue.Priority),
-			escapeSQL(issue.Type),
-			escapeSQL(issue.Resolution),
-			escapeSQL(issue.Author),
-			escapeSQL(issue.AuthorName),
-			escapeSQL(issue.Assignee),
-			escapeSQL(issue.AssigneeName),
-			issue.CreatedAt.Unix(),
-			issue.UpdatedAt.Unix(),
-		)
+		var doc document
+		doc.int("id", id)
+		doc.str("issue_key", issue.Key)
+		doc.str("tracker_id", issue.ID)
+		doc.str("url", issue.URL)
+		doc.str("summary", issue.Summary)
+		doc.str("description", issue.Description)
+		doc.str("comments_text", issue.CommentsText)
+		doc.str("queue", issue.Queue)
+		doc.str("status", issue.Status)
+		doc.str("status_name", issue.StatusName)
+		doc.str("priority", issue.Priority)
+		doc.str("type", issue.Type)
+		doc.str("resolution", issue.Resolution)
+		doc.str("author", issue.Author)
+		doc.str("author_name", issue.AuthorName)
+		doc.str("assignee", issue.Assignee)
+		doc.str("assignee_name", issue.AssigneeName)
+		doc.time("created_at", issue.CreatedAt)
+		doc.time("updated_at", issue.UpdatedAt)
+		doc.str("description_raw", issue.DescriptionRaw)
+		doc.time("synced_at", issue.SyncedAt)
+		doc.str("code_text", issue.CodeText)
+		doc.json("refs", issueRefs{Links: issue.Links, Mentions: issue.Mentions, Issues: issue.IssueRefs})
+		doc.str("parent_key", issue.Parent)
+		doc.str("epic_key", issue.Epic)
+		doc.str("ancestor_path", strings.Join(issue.Ancestors, ancestorSeparator))
+		doc.multi("ancestors", hashKeys(issue.Ancestors))
+		doc.time("status_since", statusSince)
+		attachmentNames, attachmentsText := attachmentColumns(issue.Attachments)
+		doc.str("attachment_names", attachmentNames)
+		doc.str("attachments_text", attachmentsText)
+		doc.str("worklog_text", issue.WorklogText)
+		doc.str("checklist_text", issue.ChecklistText)
+		doc.str("queue_name", issue.QueueName)
+		doc.str("priority_name", issue.PriorityName)
+		doc.int("priority_order", int64(dicts.order(tracker.DictPriority, issue.Priority)))
+		doc.str("type_name", issue.TypeName)
+		doc.str("resolution_name", issue.ResolutionName)
+		doc.str("status_category", issue.StatusCategory)
+		doc.time("deadline", issue.Deadline)
+		doc.time("start_date", issue.Start)
+		doc.time("end_date", issue.End)
+		doc.multi("components", refIDs(issue.Components))
+		doc.multi("fix_versions", refIDs(issue.FixVersions))
+		doc.multi("affected_versions", refIDs(issue.AffectedVersions))
+		doc.multi("sprints", refIDs(issue.Sprints))
+		doc.multi("follower_ids", userIDs(issue.Followers))
+		doc.json("followers", issue.Followers)
+		doc.int("follower_count", int64(len(issue.Followers)))
+		doc.int("votes", int64(issue.Votes))
+		doc.int("comment_count", int64(issue.CommentCount))
+		doc.time("last_commented_at", issue.LastCommentedAt)
+		doc.time("resolved_at", issue.ResolvedAt)
+		doc.str("issue_keys", strings.Join(append([]string{issue.Key}, issue.PreviousKeys...), " "))
+		doc.multi("key_ids", keyIDs(append([]string{issue.Key}, issue.PreviousKeys...)))
+		doc.json("previous_keys", issue.PreviousKeys)
+		doc.json("planning", issuePlanning{
+			Components:       issue.Components,
+			FixVersions:      issue.FixVersions,
+			AffectedVersions: issue.AffectedVersions,
+			Sprints:          issue.Sprints,
+		})
+		customValues := issue.CustomFields
+		if customValues == nil {
+			customValues = map[string]json.RawMessage{}
+		}
+		doc.json("custom_fields", customValues)
+		addCustomFields(&doc, issue.CustomFields)
+		if err := idx.keepUnloaded(ctx, issue, stored, &doc); err != nil {
+			return err
+		}
 
-		req := idx.client.UtilsAPI.Sql(ctx).Body(sql)
-		_, _, err = req.Execute()
-		if err != nil {
+		// TODO: why api fails as 409 and only SQL way works?
+		if _, err := idx.queryRows(ctx, replaceSQL(tableName, []document{doc})); err != nil {
 			return fmt.Errorf("replace document %s: %w", issue.Key, err)
 		}
+
+		if err := idx.dropStale(ctx, id, issue.Key, stored); err != nil {
+			return err
+		}
+
+		// stored rows of details that failed to load are kept until the next sync
+		if issue.Loaded(tracker.PartComments) {
+			if err := idx.indexComments(ctx, issue.Key, issue.Comments); err != nil {
+				return err
+			}
+		}
+
+		if issue.Loaded(tracker.PartLinks) {
+			if err := idx.indexLinks(ctx, issue.Key, issue.IssueLinks); err != nil {
+				return err
+			}
+		}
+
+		if err := idx.indexChanges(ctx, issue.Key, issue.Changes); err != nil {
+			return err
+		}
+
+		if issue.Loaded(tracker.PartAttachments) {
+			if err := idx.indexAttachments(ctx, issue.Key, issue.Attachments); err != nil {
+				return err
+			}
+		}
+
+		if issue.Loaded(tracker.PartWorklog) {
+			if err := idx.indexWorklog(ctx, issue); err != nil {
+				return err
+			}
+		}
+
+		if err := idx.indexChecklist(ctx, issue.Key, issue.Checklist); err != nil {
+			return err
+		}
+
+		// summons are found in comments, stored ones are kept with the current status
+		summons := issue.Summons
+		if !issue.Loaded(tracker.PartComments) {
+			stored, err := idx.GetSummons(ctx, issue.Key)
+			if err != nil {
+				return err
+			}
+			summons = stored
+		}
+		if err := idx.indexSummons(ctx, issue, summons); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -152,12 +235,12 @@ func (idx *Indexer) Search(ctx context.Context, query string, limit int) ([]Sear
 
 	searchSQL := fmt.Sprintf(
 		`SELECT id, issue_key, url, summary, status_name, assignee_name, 
-		        HIGHLIGHT({before_match='<b>', after_match='</b>'}, 'summary,description,comments_text') as highlight
+		        %s as highlight
 		 FROM %s 
 		 WHERE MATCH('%s')
 		 LIMIT %d
 		 OPTION ranker=proximity_bm25`,
-		tableName, escapedQuery, limit)
+		highlightExpr, tableName, escapedQuery, limit)
 
 	req := idx.client.UtilsAPI.Sql(ctx).Body(searchSQL)
 	resp, _, err := req.Execute()
@@ -213,7 +296,22 @@ type SearchResult struct {
 	AssigneeName string `json:"assignee_name"`
 	Queue        string `json:"queue"`
 	Priority     string `json:"priority"`
+	PriorityName string `json:"priority_name,omitempty"`
 	Highlight    string `json:"highlight"`
+	Group        string `json:"group,omitempty"`
+	GroupTotal   int    `json:"group_total,omitempty"`
+
+	// Deadline - planned date, zero if not set
+	Deadline       time.Time `json:"deadline,omitempty"`
+	StatusCategory string    `json:"status_category,omitempty"`
+
+	// Votes, CommentCount, LastCommentedAt - popularity and activity signals
+	Votes           int       `json:"votes"`
+	CommentCount    int       `json:"comment_count"`
+	LastCommentedAt time.Time `json:"last_commented_at,omitempty"`
+
+	// Attachments - attachments of the issue matching the query
+	Attachments []AttachmentHit `json:"attachments,omitempty"`
 }
 
 // hashString - hashes a string to an int64
@@ -286,6 +384,12 @@ func getStringFromMap(m map[string]interface{}, key string) string {
 	}
 }
 
+// getIntFromMap - safely gets an integer value from a map
+func getIntFromMap(m map[string]interface{}, key string) int {
+	n, _ := strconv.Atoi(getStringFromMap(m, key))
+	return n
+}
+
 // FilterOptions - available filter values
 type FilterOptions struct {
 	Queues     []string `json:"queues"`
@@ -293,6 +397,26 @@ type FilterOptions struct {
 	Priorities []string `json:"priorities"`
 	Authors    []string `json:"authors"`
 	Assignees  []string `json:"assignees"`
+	// StatusCategories - status categories present in the index
+	StatusCategories []string `json:"status_categories"`
+	// Components, Versions, Sprints, Boards - IDs from the planning catalog;
+	// archived sprints are left out
+	Components []string `json:"components"`
+	Versions   []string `json:"versions"`
+	Sprints    []string `json:"sprints"`
+	Boards     []string `json:"boards"`
+	// Names - localized names of values by filter parameter
+	Names map[string]map[string]string `json:"names"`
+	// CustomFields - declared custom fields with frequent values of string fields
+	CustomFields []CustomFieldOptions `json:"custom_fields"`
+}
+
+// Name - returns the localized name of a filter value, the value itself if it has none
+func (o *FilterOptions) Name(param, value string) string {
+	if name := o.Names[param][value]; name != "" {
+		return name
+	}
+	return value
 }
 
 // GetFilterOptions - returns unique values for filters
@@ -342,6 +466,31 @@ func (idx *Indexer) GetFilterOptions(ctx context.Context) (*FilterOptions, error
 		log.Printf("Error getting priorities: %v", err)
 	}
 
+	options.StatusCategories, err = getDistinct("status_category")
+	if err != nil {
+		log.Printf("Error getting status categories: %v", err)
+	}
+
+	dicts, err := idx.Dictionaries(ctx)
+	if err != nil {
+		log.Printf("Error getting dictionaries: %v", err)
+	}
+	dicts.sortByOrder(tracker.DictPriority, options.Priorities)
+	options.Components = dicts.keys(tracker.DictComponent)
+	options.Versions = dicts.keys(tracker.DictVersion)
+	options.Sprints = dicts.keys(tracker.DictSprint)
+	options.Boards = dicts.keys(tracker.DictBoard)
+	versionNames := dicts.names(tracker.DictVersion, options.Versions)
+	options.Names = map[string]map[string]string{
+		"queue":            dicts.names(tracker.DictQueue, options.Queues),
+		"priority":         dicts.names(tracker.DictPriority, options.Priorities),
+		"component":        dicts.names(tracker.DictComponent, options.Components),
+		"fix_version":      versionNames,
+		"affected_version": versionNames,
+		"sprint":           dicts.names(tracker.DictSprint, options.Sprints),
+		"current_sprint":   dicts.names(tracker.DictBoard, options.Boards),
+	}
+
 	options.Authors, err = getDistinct("author_name")
 	if err != nil {
 		log.Printf("Error getting authors: %v", err)
@@ -352,16 +501,72 @@ func (idx *Indexer) GetFilterOptions(ctx context.Context) (*FilterOptions, error
 		log.Printf("Error getting assignees: %v", err)
 	}
 
+	options.CustomFields = []CustomFieldOptions{}
+	for _, f := range customFields {
+		fo := CustomFieldOptions{CustomField: f}
+		if f.Type == CustomString {
+			fo.Values, err = getDistinct(f.Param)
+			if err != nil {
+				log.Printf("Error getting values of %s: %v", f.ID, err)
+			}
+		}
+		options.CustomFields = append(options.CustomFields, fo)
+	}
+
 	return options, nil
 }
 
 // SearchFilters - filter parameters for search
 type SearchFilters struct {
-	Queue    string
-	Status   string
-	Priority string
-	Author   string
-	Assignee string
+	Queue    FieldFilter
+	Status   FieldFilter
+	Priority FieldFilter
+	Author   FieldFilter
+	Assignee FieldFilter
+
+	// StatusCategory - open, in_progress or done, comparable across workflows
+	StatusCategory FieldFilter
+
+	// Deadline - overdue, due this week or without a deadline
+	Deadline DeadlineFilter
+
+	// Component, FixVersion, AffectedVersion, Sprint - planning references by ID or name
+	Component       FieldFilter
+	FixVersion      FieldFilter
+	AffectedVersion FieldFilter
+	Sprint          FieldFilter
+	// CurrentSprint - boards by ID or name, matches issues in their sprints in progress
+	CurrentSprint []string
+
+	// Follower - users by ID, login or name, matches issues any of them follows
+	Follower []string
+
+	// ResolvedFrom, ResolvedTo - period of resolution, open if zero; any bound excludes open issues
+	ResolvedFrom time.Time
+	ResolvedTo   time.Time
+
+	// Links - filter on links to other issues
+	Links LinkFilter
+
+	// Within - keys of epics or parent issues, matches all their descendants
+	Within []string
+
+	// History - filter on past statuses and assignees
+	History HistoryFilter
+
+	// Checklist - filter on unchecked checklist items
+	Checklist ChecklistFilter
+
+	// Custom - filters on declared custom fields by parameter name
+	Custom map[string]CustomFilter
+
+	// GroupBy - field to group results by (queue, assignee or status), empty for a flat list
+	GroupBy string
+
+	// Sort - order of results: updated (default), relevance, votes, comments or commented
+	Sort string
+	// Boost - raises relevance of voted, followed and discussed issues
+	Boost bool
 }
 
 // SearchWithFilters - performs a full-text search query with filters
@@ -370,69 +575,116 @@ func (idx *Indexer) SearchWithFilters(ctx context.Context, query string, filters
 		limit = 20
 	}
 
-	// Build WHERE conditions
-	var conditions []string
-
-	// Add full-text search if query is provided
-	if query != "" {
-		escapedQuery := escapeQuery(query)
-		conditions = append(conditions, fmt.Sprintf("MATCH('%s')", escapedQuery))
+	whereClause, err := idx.buildWhere(ctx, query, filters)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add filter conditions
-	if filters.Queue != "" {
-		conditions = append(conditions, fmt.Sprintf("queue = '%s'", escapeSQL(filters.Queue)))
+	var results []SearchResult
+	if filters.GroupBy != "" {
+		results, err = idx.searchGrouped(ctx, whereClause, filters.GroupBy, 0, limit)
+	} else {
+		selectExpr, order := resultOrder(filters, query != "")
+		results, err = idx.searchRange(ctx, whereClause, selectExpr, order, 0, limit)
 	}
-	if filters.Status != "" {
-		conditions = append(conditions, fmt.Sprintf("status_name = '%s'", escapeSQL(filters.Status)))
+	if err != nil {
+		return nil, err
 	}
-	if filters.Priority != "" {
-		conditions = append(conditions, fmt.Sprintf("priority = '%s'", escapeSQL(filters.Priority)))
+
+	if err := idx.attributeAttachments(ctx, query, results); err != nil {
+		return nil, err
 	}
-	if filters.Author != "" {
-		conditions = append(conditions, fmt.Sprintf("author_name = '%s'", escapeSQL(filters.Author)))
+	return results, nil
+}
+
+// buildWhere - builds WHERE clause from the full-text query and filters
+func (idx *Indexer) buildWhere(ctx context.Context, query string, filters SearchFilters) (string, error) {
+	var conditions []string
+
+	// Add full-text search if query is provided, with words required in custom text fields
+	var match []string
+	if query != "" {
+		match = append(match, escapeQuery(query))
 	}
-	if filters.Assignee != "" {
-		conditions = append(conditions, fmt.Sprintf("assignee_name = '%s'", escapeSQL(filters.Assignee)))
+	match = append(match, customMatch(filters.Custom)...)
+	if len(match) > 0 {
+		conditions = append(conditions, fmt.Sprintf("MATCH('%s')", strings.Join(match, " ")))
 	}
 
-	// Build the query
-	whereClause := ""
-	if len(conditions) > 0 {
-		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	// Add filter conditions
+	filterConditions, err := idx.conditions(ctx, filters)
+	if err != nil {
+		return "", fmt.Errorf("filters: %w", err)
 	}
+	conditions = append(conditions, filterConditions...)
 
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), nil
+}
+
+// searchRange - returns a slice of matching issues in the order, with an extra select expression
+// the order may refer to
+func (idx *Indexer) searchRange(ctx context.Context, whereClause, selectExpr, order string, offset, limit int) ([]SearchResult, error) {
 	searchSQL := fmt.Sprintf(
-		`SELECT id, issue_key, url, summary, status_name, assignee_name, queue, priority,
-		        HIGHLIGHT({before_match='<b>', after_match='</b>'}, 'summary,description,comments_text') as highlight
+		`SELECT id, issue_key, url, summary, status_name, assignee_name, queue, priority, priority_name, deadline, status_category,
+		        votes, comment_count, last_commented_at,
+		        %s as highlight%s
 		 FROM %s 
 		 %s
-		 ORDER BY updated_at DESC
-		 LIMIT %d`,
-		tableName, whereClause, limit)
+		 ORDER BY %s
+		 LIMIT %d, %d
+		 OPTION max_matches=%d`,
+		highlightExpr, selectExpr, tableName, whereClause, order, offset, limit, offset+limit)
 
-	req := idx.client.UtilsAPI.Sql(ctx).Body(searchSQL)
-	resp, _, err := req.Execute()
+	rows, err := idx.queryRows(ctx, searchSQL)
 	if err != nil {
 		return nil, fmt.Errorf("search: %w", err)
 	}
 
 	var results []SearchResult
+	for _, rowMap := range rows {
+		result := extractRow(rowMap)
+		result.Queue = getStringFromMap(rowMap, "queue")
+		result.Priority = getStringFromMap(rowMap, "priority")
+		result.PriorityName = getStringFromMap(rowMap, "priority_name")
+		result.Deadline = getTimeFromMap(rowMap, "deadline")
+		result.StatusCategory = getStringFromMap(rowMap, "status_category")
+		result.Votes = getIntFromMap(rowMap, "votes")
+		result.CommentCount = getIntFromMap(rowMap, "comment_count")
+		result.LastCommentedAt = getTimeFromMap(rowMap, "last_commented_at")
+		results = append(results, result)
+	}
 
-	if resp.ArrayOfMapmapOfStringAny != nil {
-		for _, queryResult := range *resp.ArrayOfMapmapOfStringAny {
-			if dataRows, ok := queryResult["data"].([]interface{}); ok {
-				for _, rowRaw := range dataRows {
-					if rowMap, ok := rowRaw.(map[string]interface{}); ok {
-						result := extractRow(rowMap)
-						result.Queue = getStringFromMap(rowMap, "queue")
-						result.Priority = getStringFromMap(rowMap, "priority")
-						results = append(results, result)
-					}
+	return results, nil
+}
+
+// queryRows - executes an SQL query and returns rows of all result sets
+func (idx *Indexer) queryRows(ctx context.Context, sql string) ([]map[string]interface{}, error) {
+	req := idx.client.UtilsAPI.Sql(ctx).Body(sql)
+	resp, _, err := req.Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	if resp.ArrayOfMapmapOfStringAny == nil {
+		return nil, nil
+	}
+
+	for _, queryResult := range *resp.ArrayOfMapmapOfStringAny {
+		if msg := getStringFromMap(queryResult, "error"); msg != "" {
+			return nil, fmt.Errorf("manticore: %s", msg)
+		}
+		if dataRows, ok := queryResult["data"].([]interface{}); ok {
+			for _, rowRaw := range dataRows {
+				if rowMap, ok := rowRaw.(map[string]interface{}); ok {
+					rows = append(rows, rowMap)
 				}
 			}
 		}
 	}
 
-	return results, nil
+	return rows, nil
 }
@@ -0,0 +1,69 @@
+// Package indexertest provides a fake Manticore server for in-process tests of the indexer users
+package indexertest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Handler - answers an SQL query with result rows, or fails it
+type Handler func(sql string) ([]map[string]any, error)
+
+// Server - fake Manticore answering the SQL endpoint with rows of the handler
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	queries []string
+}
+
+// NewServer - starts a fake Manticore closed with the test
+func NewServer(t testing.TB, handler Handler) *Server {
+	t.Helper()
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sql" {
+			http.NotFound(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sql := string(body)
+
+		s.mu.Lock()
+		s.queries = append(s.queries, sql)
+		s.mu.Unlock()
+
+		// Manticore reports query errors in the result set
+		result := map[string]any{"total": 0, "error": "", "warning": ""}
+		rows, err := handler(sql)
+		if err != nil {
+			result["error"] = err.Error()
+		} else {
+			if rows == nil {
+				rows = []map[string]any{}
+			}
+			result["data"] = rows
+			result["total"] = len(rows)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]map[string]any{result})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Queries - returns SQL queries received so far
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
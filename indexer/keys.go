@@ -0,0 +1,173 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"ytbs/tracker"
+)
+
+// keyTables - tables with rows of an issue by its key, and their key columns
+var keyTables = []struct {
+	name   string
+	column string
+}{
+	{commentsTableName, "issue_key"},
+	{linksTableName, "source_key"},
+	{changesTableName, "issue_key"},
+	{attachmentsTableName, "issue_key"},
+	{worklogTableName, "issue_key"},
+	{checklistTableName, "issue_key"},
+	{summonsTableName, "issue_key"},
+}
+
+// storedKeys - identity of an indexed issue
+type storedKeys struct {
+	id           int64
+	key          string
+	previousKeys []string
+}
+
+// keyIDs - hashes issue keys for lookups by current and previous keys
+func keyIDs(keys []string) []int64 {
+	var ids []int64
+	for _, key := range uniqueValues(keys) {
+		if key = strings.ToUpper(strings.TrimSpace(key)); key != "" {
+			ids = append(ids, hashString(key))
+		}
+	}
+	return ids
+}
+
+// keyIDsCondition - matches issues known under any of the keys
+func keyIDsCondition(keys []string) string {
+	ids := keyIDs(keys)
+	if len(ids) == 0 {
+		return "id < 0"
+	}
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = fmt.Sprintf("%d", id)
+	}
+	return fmt.Sprintf("ANY(key_ids) IN (%s)", strings.Join(values, ", "))
+}
+
+// findByKeys - returns indexed issues known under any of the keys, as current or previous ones.
+// Issues indexed before previous keys were tracked are found by their current key.
+// Key hashes may collide, so matches are confirmed by the stored keys
+func (idx *Indexer) findByKeys(ctx context.Context, keys []string) ([]storedKeys, error) {
+	var found []storedKeys
+	seen := make(map[int64]bool)
+	for _, condition := range []string{keysCondition(keys), keyIDsCondition(keys)} {
+		rows, err := idx.queryRows(ctx, fmt.Sprintf(
+			`SELECT id, issue_key, previous_keys FROM %s WHERE %s LIMIT 100`, tableName, condition))
+		if err != nil {
+			return nil, fmt.Errorf("find issues by keys: %w", err)
+		}
+		for _, row := range rows {
+			s := storedKeys{
+				id:  int64(getIntFromMap(row, "id")),
+				key: getStringFromMap(row, "issue_key"),
+			}
+			if seen[s.id] {
+				continue
+			}
+			getJSONFromMap(row, "previous_keys", &s.previousKeys)
+			if !s.knownAs(keys) {
+				continue
+			}
+			seen[s.id] = true
+			found = append(found, s)
+		}
+	}
+	return found, nil
+}
+
+// knownAs - checks if the issue is stored under any of the keys, as current or previous one
+func (s storedKeys) knownAs(keys []string) bool {
+	for _, key := range append([]string{s.key}, s.previousKeys...) {
+		if containsKey(keys, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeKeys - adds keys the issue was indexed under before to its previous keys,
+// since the changelog of a moved issue is fetched incrementally and aliases may be removed
+func mergeKeys(issue *tracker.IndexedIssue, stored []storedKeys) {
+	keys := issue.PreviousKeys
+	for _, s := range stored {
+		keys = append(keys, s.key)
+		keys = append(keys, s.previousKeys...)
+	}
+
+	merged := make([]string, 0, len(keys))
+	for _, key := range uniqueValues(keys) {
+		if key != "" && key != issue.Key {
+			merged = append(merged, key)
+		}
+	}
+	issue.PreviousKeys = nil
+	if len(merged) > 0 {
+		issue.PreviousKeys = merged
+	}
+}
+
+// dropStale - removes documents left from the issue under its previous keys:
+// other documents of the issue and rows of related tables stored by its old keys.
+// Documents are matched by key, since IDs of legacy documents are not read back exactly
+func (idx *Indexer) dropStale(ctx context.Context, id int64, issueKey string, stored []storedKeys) error {
+	keys := []string{issueKey}
+	for _, s := range stored {
+		keys = append(keys, s.key)
+	}
+	for _, key := range uniqueValues(keys) {
+		if key == "" {
+			continue
+		}
+		deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE issue_key = '%s' AND id != %d`, tableName, escapeSQL(key), id)
+		if _, err := idx.queryRows(ctx, deleteSQL); err != nil {
+			return fmt.Errorf("delete stale documents of %s: %w", key, err)
+		}
+		if key == issueKey {
+			continue
+		}
+		// rows are stored again under the current key
+		for _, t := range keyTables {
+			deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE %s = '%s'`, t.name, t.column, escapeSQL(key))
+			if _, err := idx.queryRows(ctx, deleteSQL); err != nil {
+				return fmt.Errorf("delete %s of %s: %w", t.name, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveKey - returns the current key of an issue known under the key, as current or previous one
+func (idx *Indexer) ResolveKey(ctx context.Context, key string) (string, error) {
+	stored, err := idx.findByKeys(ctx, []string{key})
+	if err != nil {
+		return "", err
+	}
+	for _, s := range stored {
+		if s.key == key {
+			return key, nil
+		}
+	}
+	if len(stored) == 0 {
+		return "", fmt.Errorf("issue %s: %w", key, ErrNotFound)
+	}
+	return stored[0].key, nil
+}
+
+// resolveKey - returns the current key of the issue, or the key itself if no issue is known under it
+func (idx *Indexer) resolveKey(ctx context.Context, key string) (string, error) {
+	current, err := idx.ResolveKey(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return key, nil
+	}
+	return current, err
+}
@@ -0,0 +1,343 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"ytbs/tracker"
+)
+
+const (
+	// maxLinkRows - upper bound of link rows read by a single query
+	maxLinkRows = 10000
+	// MaxGraphDepth - upper bound of the graph neighborhood depth
+	MaxGraphDepth = 5
+)
+
+// indexLinks - replaces stored links of an issue
+func (idx *Indexer) indexLinks(ctx context.Context, issueKey string, links []tracker.IndexedLink) error {
+	deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE source_key = '%s'`, linksTableName, escapeSQL(issueKey))
+	if _, err := idx.queryRows(ctx, deleteSQL); err != nil {
+		return fmt.Errorf("delete links of %s: %w", issueKey, err)
+	}
+
+	if len(links) == 0 {
+		return nil
+	}
+
+	docs := make([]document, 0, len(links))
+	for _, l := range links {
+		var doc document
+		doc.int("id", documentID(issueKey+"|"+l.Relation+"|"+l.Key))
+		doc.str("source_key", issueKey)
+		doc.str("target_key", l.Key)
+		doc.str("target_display", l.Display)
+		doc.str("relation", l.Relation)
+		doc.str("link_type", l.Type)
+		doc.str("direction", l.Direction)
+		docs = append(docs, doc)
+	}
+
+	if _, err := idx.queryRows(ctx, replaceSQL(linksTableName, docs)); err != nil {
+		return fmt.Errorf("replace links of %s: %w", issueKey, err)
+	}
+	return nil
+}
+
+// linkRow - stored link
+type linkRow struct {
+	source   string
+	target   string
+	display  string
+	relation string
+	linkType string
+	direct   string
+}
+
+// queryLinks - reads stored links matching the conditions
+func (idx *Indexer) queryLinks(ctx context.Context, conditions ...string) ([]linkRow, error) {
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT source_key, target_key, target_display, relation, link_type, direction FROM %s %s LIMIT %d OPTION max_matches=%d`,
+		linksTableName, whereClause, maxLinkRows, maxLinkRows))
+	if err != nil {
+		return nil, fmt.Errorf("query links: %w", err)
+	}
+
+	links := make([]linkRow, 0, len(rows))
+	for _, row := range rows {
+		links = append(links, linkRow{
+			source:   getStringFromMap(row, "source_key"),
+			target:   getStringFromMap(row, "target_key"),
+			display:  getStringFromMap(row, "target_display"),
+			relation: getStringFromMap(row, "relation"),
+			linkType: getStringFromMap(row, "link_type"),
+			direct:   getStringFromMap(row, "direction"),
+		})
+	}
+	return links, nil
+}
+
+// GetLinks - returns stored links of an issue
+func (idx *Indexer) GetLinks(ctx context.Context, issueKey string) ([]tracker.IndexedLink, error) {
+	rows, err := idx.queryLinks(ctx, fmt.Sprintf("source_key = '%s'", escapeSQL(issueKey)))
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]tracker.IndexedLink, 0, len(rows))
+	for _, r := range rows {
+		links = append(links, tracker.IndexedLink{
+			Relation:  r.relation,
+			Type:      r.linkType,
+			Direction: r.direct,
+			Key:       r.target,
+			Display:   r.display,
+		})
+	}
+	return links, nil
+}
+
+// openIssues - returns the subset of keys that are indexed and have no resolution
+func (idx *Indexer) openIssues(ctx context.Context, keys []string) (map[string]bool, error) {
+	open := make(map[string]bool)
+	if len(keys) == 0 {
+		return open, nil
+	}
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT issue_key FROM %s WHERE %s AND resolution = '' LIMIT %d OPTION max_matches=%d`,
+		tableName, FieldFilter{Values: keys}.condition("issue_key"), len(keys), len(keys)))
+	if err != nil {
+		return nil, fmt.Errorf("query open issues: %w", err)
+	}
+
+	for _, row := range rows {
+		open[getStringFromMap(row, "issue_key")] = true
+	}
+	return open, nil
+}
+
+// linkCondition - resolves a link filter to a condition on issue keys
+func (idx *Indexer) linkCondition(ctx context.Context, f LinkFilter) (string, error) {
+	var conditions []string
+	if len(f.To) > 0 {
+		conditions = append(conditions, FieldFilter{Values: f.To}.condition("target_key"))
+	}
+	if len(f.Relations) > 0 {
+		conditions = append(conditions, FieldFilter{Values: f.Relations}.condition("relation"))
+	}
+
+	links, err := idx.queryLinks(ctx, conditions...)
+	if err != nil {
+		return "", err
+	}
+
+	// links from issues that are not synced are known only from the other side
+	if len(f.To) > 0 {
+		reverse := []string{FieldFilter{Values: f.To}.condition("source_key")}
+		if len(f.Relations) > 0 {
+			inverse := make([]string, len(f.Relations))
+			for i, r := range f.Relations {
+				inverse[i] = tracker.InverseRelation(r)
+			}
+			reverse = append(reverse, FieldFilter{Values: inverse}.condition("relation"))
+		}
+
+		reversed, err := idx.queryLinks(ctx, reverse...)
+		if err != nil {
+			return "", err
+		}
+		for _, l := range reversed {
+			links = append(links, linkRow{source: l.target, target: l.source, relation: tracker.InverseRelation(l.relation)})
+		}
+	}
+
+	var open map[string]bool
+	if f.OpenTargets {
+		targets := make([]string, 0, len(links))
+		for _, l := range links {
+			targets = append(targets, l.target)
+		}
+		if open, err = idx.openIssues(ctx, uniqueValues(targets)); err != nil {
+			return "", err
+		}
+	}
+
+	var sources []string
+	for _, l := range links {
+		if open == nil || open[l.target] {
+			sources = append(sources, l.source)
+		}
+	}
+
+	if len(sources) == 0 {
+		// nothing is linked, match no documents
+		return "id < 0", nil
+	}
+	return FieldFilter{Values: uniqueValues(sources)}.condition("issue_key"), nil
+}
+
+// GraphNode - issue in a link graph; issues outside of the index have only a key
+type GraphNode struct {
+	Key        string `json:"key"`
+	Summary    string `json:"summary,omitempty"`
+	StatusName string `json:"status_name,omitempty"`
+	Resolved   bool   `json:"resolved"`
+	Indexed    bool   `json:"indexed"`
+	Depth      int    `json:"depth"`
+}
+
+// GraphEdge - directed link between two issues
+type GraphEdge struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Relation string `json:"relation"`
+}
+
+// LinkGraph - neighborhood of an issue in the link graph
+type LinkGraph struct {
+	Root  string      `json:"root"`
+	Depth int         `json:"depth"`
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// LinkGraph - returns issues within depth links from the root issue and links between them
+func (idx *Indexer) LinkGraph(ctx context.Context, root string, depth int) (*LinkGraph, error) {
+	if depth <= 0 {
+		depth = 1
+	}
+	if depth > MaxGraphDepth {
+		depth = MaxGraphDepth
+	}
+	root, err := idx.resolveKey(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &LinkGraph{Root: root, Depth: depth}
+	depths := map[string]int{root: 0}
+	order := []string{root}
+	edges := make(map[GraphEdge]bool)
+
+	frontier := []string{root}
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		keys := FieldFilter{Values: frontier}
+
+		// links are stored on both sides only if both issues are synced, so look both ways
+		outgoing, err := idx.queryLinks(ctx, keys.condition("source_key"))
+		if err != nil {
+			return nil, err
+		}
+		incoming, err := idx.queryLinks(ctx, keys.condition("target_key"))
+		if err != nil {
+			return nil, err
+		}
+
+		var next []string
+		visit := func(key string) {
+			if _, seen := depths[key]; !seen {
+				depths[key] = level
+				order = append(order, key)
+				next = append(next, key)
+			}
+		}
+
+		for _, l := range outgoing {
+			edges[normalizeEdge(l.source, l.target, l.relation)] = true
+			visit(l.target)
+		}
+		for _, l := range incoming {
+			edges[normalizeEdge(l.source, l.target, l.relation)] = true
+			visit(l.source)
+		}
+
+		frontier = next
+	}
+
+	nodes, err := idx.graphNodes(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range order {
+		node := nodes[key]
+		node.Key = key
+		node.Depth = depths[key]
+		graph.Nodes = append(graph.Nodes, node)
+	}
+
+	for e := range edges {
+		// edges to issues beyond the depth limit are dropped
+		if _, ok := depths[e.Source]; !ok {
+			continue
+		}
+		if _, ok := depths[e.Target]; !ok {
+			continue
+		}
+		graph.Edges = append(graph.Edges, e)
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		a, b := graph.Edges[i], graph.Edges[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Relation < b.Relation
+	})
+
+	return graph, nil
+}
+
+// normalizeEdge - orients links so that the same link seen from both issues is one edge
+func normalizeEdge(source, target, relation string) GraphEdge {
+	inverse := tracker.InverseRelation(relation)
+	if (inverse != relation && relation > inverse) || (inverse == relation && source > target) {
+		return GraphEdge{Source: target, Target: source, Relation: inverse}
+	}
+	return GraphEdge{Source: source, Target: target, Relation: relation}
+}
+
+// graphNodes - loads indexed fields of graph nodes
+func (idx *Indexer) graphNodes(ctx context.Context, keys []string) (map[string]GraphNode, error) {
+	nodes := make(map[string]GraphNode, len(keys))
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT issue_key, summary, status_name, resolution FROM %s WHERE %s LIMIT %d OPTION max_matches=%d`,
+		tableName, FieldFilter{Values: keys}.condition("issue_key"), len(keys), len(keys)))
+	if err != nil {
+		return nil, fmt.Errorf("query graph nodes: %w", err)
+	}
+
+	for _, row := range rows {
+		key := getStringFromMap(row, "issue_key")
+		nodes[key] = GraphNode{
+			Summary:    getStringFromMap(row, "summary"),
+			StatusName: getStringFromMap(row, "status_name"),
+			Resolved:   getStringFromMap(row, "resolution") != "",
+			Indexed:    true,
+		}
+	}
+	return nodes, nil
+}
+
+// uniqueValues - removes duplicates preserving order
+func uniqueValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,335 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"ytbs/tracker"
+)
+
+// maxPageSize - upper bound for a single page of results
+const maxPageSize = 200
+
+// ErrNotFound - requested document is not in the index
+var ErrNotFound = errors.New("not found")
+
+// FacetValue - field value with the number of matching issues
+type FacetValue struct {
+	Value string `json:"value"`
+	// Name - localized name of a dictionary value, e.g. a priority
+	Name  string `json:"name,omitempty"`
+	Count int    `json:"count"`
+}
+
+// SearchPage - page of search results with total count and facets
+type SearchPage struct {
+	Total   int                     `json:"total"`
+	Offset  int                     `json:"offset"`
+	Limit   int                     `json:"limit"`
+	Results []SearchResult          `json:"results"`
+	Groups  []ResultGroup           `json:"groups,omitempty"`
+	Facets  map[string][]FacetValue `json:"facets,omitempty"`
+}
+
+// IsFacetable - checks if the field can be used as a facet
+func IsFacetable(field string) bool {
+	_, ok := facetColumn(field)
+	return ok
+}
+
+// facetColumn - returns the table column of a facet field: a filter field or a declared custom field
+func facetColumn(field string) (string, bool) {
+	for _, ff := range filterFields {
+		if ff.param == field {
+			return ff.column, true
+		}
+	}
+	if f, ok := customField(field); ok && f.Facetable() {
+		return f.Param, true
+	}
+	return "", false
+}
+
+// SearchPaged - performs a search returning one page of results, total count and
+// value counts of the requested facet fields
+func (idx *Indexer) SearchPaged(ctx context.Context, query string, filters SearchFilters, offset, limit int, facets []string) (*SearchPage, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	whereClause, err := idx.buildWhere(ctx, query, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &SearchPage{
+		Offset: offset,
+		Limit:  limit,
+	}
+
+	if filters.GroupBy != "" {
+		page.Results, err = idx.searchGrouped(ctx, whereClause, filters.GroupBy, offset, limit)
+	} else {
+		selectExpr, order := resultOrder(filters, query != "")
+		page.Results, err = idx.searchRange(ctx, whereClause, selectExpr, order, offset, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := idx.attributeAttachments(ctx, query, page.Results); err != nil {
+		return nil, err
+	}
+	if filters.GroupBy != "" {
+		page.Groups = GroupResults(page.Results)
+	}
+
+	page.Total, err = idx.count(ctx, whereClause)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range facets {
+		if !IsFacetable(field) {
+			return nil, fmt.Errorf("unsupported facet field %q", field)
+		}
+		values, err := idx.facet(ctx, whereClause, field)
+		if err != nil {
+			return nil, fmt.Errorf("facet %s: %w", field, err)
+		}
+		if page.Facets == nil {
+			page.Facets = make(map[string][]FacetValue)
+		}
+		page.Facets[field] = values
+	}
+
+	return page, nil
+}
+
+// count - returns number of issues matching the WHERE clause
+func (idx *Indexer) count(ctx context.Context, whereClause string) (int, error) {
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(`SELECT COUNT(*) as cnt FROM %s %s`, tableName, whereClause))
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return getIntFromMap(rows[0], "cnt"), nil
+}
+
+// facet - returns value counts of a filter field for issues matching the WHERE clause
+func (idx *Indexer) facet(ctx context.Context, whereClause, field string) ([]FacetValue, error) {
+	column, _ := facetColumn(field)
+	custom, isCustom := customField(field)
+
+	// grouping by an MVA returns each value by GROUPBY() rather than the column
+	selectExpr, valueColumn := column, column
+	if isRefField(field) {
+		selectExpr, valueColumn = "GROUPBY() as ref_id", "ref_id"
+	}
+
+	if isCustom && custom.Type == CustomNumber {
+		// issues without the number are stored with zero and not counted
+		setCondition := fmt.Sprintf("%s%s = 1", column, customSetSuffix)
+		if whereClause == "" {
+			whereClause = "WHERE " + setCondition
+		} else {
+			whereClause += " AND " + setCondition
+		}
+	}
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT %s, COUNT(*) as cnt FROM %s %s GROUP BY %s ORDER BY cnt DESC LIMIT 100`,
+		selectExpr, tableName, whereClause, column))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]FacetValue, 0, len(rows))
+	for _, row := range rows {
+		value := getStringFromMap(row, valueColumn)
+		if isCustom && custom.Type == CustomNumber {
+			// getStringFromMap rounds floats
+			value = displayValue(row[column])
+		}
+		values = append(values, FacetValue{
+			Value: value,
+			Count: getIntFromMap(row, "cnt"),
+		})
+	}
+
+	kind, ok := facetDictionaries[field]
+	if !ok {
+		return values, nil
+	}
+	dicts, err := idx.Dictionaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range values {
+		values[i].Name = dicts.Name(kind, values[i].Value)
+	}
+	if kind == tracker.DictPriority {
+		// priorities read better in their own order than by count
+		sort.SliceStable(values, func(i, j int) bool {
+			return dicts.less(kind, values[i].Value, values[j].Value)
+		})
+	}
+	return values, nil
+}
+
+// GetIssue - returns the indexed issue by its current or previous key
+func (idx *Indexer) GetIssue(ctx context.Context, key string) (*tracker.IndexedIssue, error) {
+	key, err := idx.ResolveKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT * FROM %s WHERE issue_key = '%s' LIMIT 1`, tableName, escapeSQL(key)))
+	if err != nil {
+		return nil, fmt.Errorf("get issue %s: %w", key, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("issue %s: %w", key, ErrNotFound)
+	}
+
+	row := rows[0]
+	issue := &tracker.IndexedIssue{
+		ID:           getStringFromMap(row, "tracker_id"),
+		Key:          getStringFromMap(row, "issue_key"),
+		URL:          getStringFromMap(row, "url"),
+		Summary:      getStringFromMap(row, "summary"),
+		Description:  getStringFromMap(row, "description"),
+		CommentsText: getStringFromMap(row, "comments_text"),
+		Queue:        getStringFromMap(row, "queue"),
+		Status:       getStringFromMap(row, "status"),
+		StatusName:   getStringFromMap(row, "status_name"),
+		Priority:     getStringFromMap(row, "priority"),
+		Type:         getStringFromMap(row, "type"),
+		Resolution:   getStringFromMap(row, "resolution"),
+		Author:       getStringFromMap(row, "author"),
+		AuthorName:   getStringFromMap(row, "author_name"),
+		Assignee:     getStringFromMap(row, "assignee"),
+		AssigneeName: getStringFromMap(row, "assignee_name"),
+		CreatedAt:    getTimeFromMap(row, "created_at"),
+		UpdatedAt:    getTimeFromMap(row, "updated_at"),
+
+		DescriptionRaw: getStringFromMap(row, "description_raw"),
+		SyncedAt:       getTimeFromMap(row, "synced_at"),
+		CodeText:       getStringFromMap(row, "code_text"),
+		WorklogText:    getStringFromMap(row, "worklog_text"),
+		ChecklistText:  getStringFromMap(row, "checklist_text"),
+
+		QueueName:      getStringFromMap(row, "queue_name"),
+		PriorityName:   getStringFromMap(row, "priority_name"),
+		TypeName:       getStringFromMap(row, "type_name"),
+		ResolutionName: getStringFromMap(row, "resolution_name"),
+		StatusCategory: getStringFromMap(row, "status_category"),
+		ResolvedAt:     getTimeFromMap(row, "resolved_at"),
+		Deadline:       getTimeFromMap(row, "deadline"),
+		Start:          getTimeFromMap(row, "start_date"),
+		End:            getTimeFromMap(row, "end_date"),
+
+		Parent:    getStringFromMap(row, "parent_key"),
+		Epic:      getStringFromMap(row, "epic_key"),
+		Ancestors: splitAncestorPath(getStringFromMap(row, "ancestor_path")),
+	}
+
+	getJSONFromMap(row, "custom_fields", &issue.CustomFields)
+	getJSONFromMap(row, "previous_keys", &issue.PreviousKeys)
+
+	getJSONFromMap(row, "followers", &issue.Followers)
+	issue.Votes = getIntFromMap(row, "votes")
+	issue.CommentCount = getIntFromMap(row, "comment_count")
+	issue.LastCommentedAt = getTimeFromMap(row, "last_commented_at")
+
+	var planning issuePlanning
+	getJSONFromMap(row, "planning", &planning)
+	issue.Components, issue.FixVersions = planning.Components, planning.FixVersions
+	issue.AffectedVersions, issue.Sprints = planning.AffectedVersions, planning.Sprints
+
+	var refs issueRefs
+	getJSONFromMap(row, "refs", &refs)
+	issue.Links, issue.Mentions, issue.IssueRefs = refs.Links, refs.Mentions, refs.Issues
+
+	issue.Comments, err = idx.GetComments(ctx, issue.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	issue.IssueLinks, err = idx.GetLinks(ctx, issue.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	issue.Changes, err = idx.GetChanges(ctx, issue.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	issue.Attachments, err = idx.GetAttachments(ctx, issue.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	issue.Worklog, err = idx.GetWorklog(ctx, issue.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	issue.Checklist, err = idx.GetChecklist(ctx, issue.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	issue.Summons, err = idx.GetSummons(ctx, issue.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return issue, nil
+}
+
+// issueRefs - references stored in the refs JSON attribute
+type issueRefs struct {
+	Links    []string `json:"links,omitempty"`
+	Mentions []string `json:"mentions,omitempty"`
+	Issues   []string `json:"issues,omitempty"`
+}
+
+// getJSONFromMap - decodes a JSON attribute value from a map into out
+func getJSONFromMap(m map[string]interface{}, key string, out any) {
+	var data []byte
+	switch v := m[key].(type) {
+	case nil:
+		return
+	case string:
+		data = []byte(v)
+	default:
+		// Manticore returns JSON attributes as nested values
+		data, _ = json.Marshal(v)
+	}
+	json.Unmarshal(data, out)
+}
+
+// getTimeFromMap - safely gets a unix timestamp value from a map
+func getTimeFromMap(m map[string]interface{}, key string) time.Time {
+	ts, err := strconv.ParseInt(getStringFromMap(m, key), 10, 64)
+	if err != nil || ts <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
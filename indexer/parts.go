@@ -0,0 +1,57 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"ytbs/tracker"
+)
+
+// partColumns - issue columns derived from details loaded with separate requests
+var partColumns = []struct {
+	part    tracker.IssuePart
+	columns []string
+}{
+	{tracker.PartComments, []string{"comments_text", "code_text", "refs", "comment_count", "last_commented_at"}},
+	{tracker.PartAttachments, []string{"attachment_names", "attachments_text"}},
+	{tracker.PartWorklog, []string{"worklog_text"}},
+}
+
+// keepUnloaded - sets columns derived from details that failed to load to their stored values,
+// so that a failed request doesn't clear them until the issue is synced again.
+// The issue may still be stored under one of its previous keys
+func (idx *Indexer) keepUnloaded(ctx context.Context, issue tracker.IndexedIssue, stored []storedKeys, doc *document) error {
+	var names []string
+	for _, p := range partColumns {
+		if !issue.Loaded(p.part) {
+			names = append(names, p.columns...)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	keys := []string{issue.Key}
+	for _, s := range stored {
+		keys = append(keys, s.key)
+	}
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE %s ORDER BY synced_at DESC LIMIT 1`,
+		strings.Join(names, ", "), tableName, FieldFilter{Values: uniqueValues(keys)}.condition("issue_key")))
+	if err != nil {
+		return fmt.Errorf("read stored details of %s: %w", issue.Key, err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	var columns []column
+	for _, c := range issueColumns() {
+		if slices.Contains(names, c.name) {
+			columns = append(columns, c)
+		}
+	}
+	doc.set(rowDocument(columns, rows[0]))
+	return nil
+}
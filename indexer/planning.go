@@ -0,0 +1,110 @@
+package indexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"ytbs/tracker"
+)
+
+// issuePlanning - planning references stored in the planning JSON attribute
+type issuePlanning struct {
+	Components       []tracker.IndexedRef `json:"components,omitempty"`
+	FixVersions      []tracker.IndexedRef `json:"fix_versions,omitempty"`
+	AffectedVersions []tracker.IndexedRef `json:"affected_versions,omitempty"`
+	Sprints          []tracker.IndexedRef `json:"sprints,omitempty"`
+}
+
+// isRefField - checks if the filter field is an MVA column of Tracker IDs
+func isRefField(param string) bool {
+	for _, ff := range filterFields {
+		if ff.param == param {
+			return ff.dict != ""
+		}
+	}
+	return false
+}
+
+// refID - converts a Tracker ID to a numeric MVA value; non-numeric IDs are hashed
+func refID(id string) int64 {
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+		return n
+	}
+	return hashString(id)
+}
+
+// refIDs - converts references to numeric MVA values
+func refIDs(refs []tracker.IndexedRef) []int64 {
+	result := make([]int64, len(refs))
+	for i, r := range refs {
+		result[i] = refID(r.ID)
+	}
+	return result
+}
+
+// resolve - converts filter values to IDs: a value is an ID or a name of dictionary entries
+func (d *Dictionaries) resolve(kind string, values []string) []int64 {
+	var ids []int64
+	for _, v := range values {
+		matched := false
+		if d != nil {
+			for _, e := range d.entries[kind] {
+				if e.Key == v || e.Names.Has(v) {
+					ids = append(ids, refID(e.Key))
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			ids = append(ids, refID(v))
+		}
+	}
+	return ids
+}
+
+// currentSprints - returns IDs of sprints in progress on the boards given by ID or name
+func (d *Dictionaries) currentSprints(boards []string) []int64 {
+	if d == nil {
+		return nil
+	}
+	boardIDs := make(map[string]bool)
+	for _, id := range d.resolve(tracker.DictBoard, boards) {
+		boardIDs[strconv.FormatInt(id, 10)] = true
+	}
+
+	var ids []int64
+	for _, e := range d.entries[tracker.DictSprint] {
+		if e.Status == tracker.SprintInProgress && boardIDs[e.Parent] {
+			ids = append(ids, refID(e.Key))
+		}
+	}
+	return ids
+}
+
+// refsCondition - builds a condition on an MVA column of IDs
+func refsCondition(column string, op FilterOp, ids []int64) string {
+	switch op {
+	case OpEmpty:
+		return fmt.Sprintf("LENGTH(%s) = 0", column)
+	case OpNotEmpty:
+		return fmt.Sprintf("LENGTH(%s) > 0", column)
+	}
+
+	if len(ids) == 0 {
+		if op == OpNotIn {
+			return "id > 0"
+		}
+		// nothing matches, e.g. a board without a sprint in progress
+		return "id < 0"
+	}
+
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.FormatInt(id, 10)
+	}
+	if op == OpNotIn {
+		return fmt.Sprintf("ALL(%s) NOT IN (%s)", column, strings.Join(values, ", "))
+	}
+	return fmt.Sprintf("ANY(%s) IN (%s)", column, strings.Join(values, ", "))
+}
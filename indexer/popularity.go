@@ -0,0 +1,96 @@
+package indexer
+
+import (
+	"fmt"
+	"strings"
+
+	"ytbs/tracker"
+)
+
+// result sort orders
+const (
+	// SortUpdated - recently updated first, the default
+	SortUpdated = "updated"
+	// SortRelevance - best matches of the full-text query first
+	SortRelevance = "relevance"
+	// SortVotes - most voted first
+	SortVotes = "votes"
+	// SortComments - most discussed first
+	SortComments = "comments"
+	// SortCommented - recently commented first
+	SortCommented = "commented"
+	// SortPriority - highest priority first, by the Tracker order of priorities
+	SortPriority = "priority"
+)
+
+// sortOrders - ORDER BY clauses of the sort orders
+var sortOrders = map[string]string{
+	SortUpdated:   "updated_at DESC",
+	SortRelevance: "WEIGHT() DESC, updated_at DESC",
+	SortVotes:     "votes DESC, updated_at DESC",
+	SortComments:  "comment_count DESC, updated_at DESC",
+	SortCommented: "last_commented_at DESC, updated_at DESC",
+	SortPriority:  "priority_order DESC, updated_at DESC",
+}
+
+// popularityExpr - relevance raised for voted, followed and discussed issues;
+// logarithms keep a few very popular issues from burying better matches
+const popularityExpr = "WEIGHT() * (1 + 0.2 * LN(1 + votes) + 0.1 * LN(1 + follower_count) + 0.1 * LN(1 + comment_count))"
+
+// IsSortable - checks if the results can be sorted in the order
+func IsSortable(order string) bool {
+	_, ok := sortOrders[order]
+	return ok
+}
+
+// resultOrder - returns an extra select expression and the ORDER BY clause of results.
+// Relevance needs a full-text query; the popularity boost implies sorting by relevance
+func resultOrder(f SearchFilters, hasQuery bool) (selectExpr, order string) {
+	sort := f.Sort
+	if sort == "" && f.Boost {
+		sort = SortRelevance
+	}
+	if sort == "" || (sort == SortRelevance && !hasQuery) {
+		sort = SortUpdated
+	}
+
+	if sort == SortRelevance && f.Boost {
+		return ", " + popularityExpr + " as score", "score DESC, updated_at DESC"
+	}
+	return "", sortOrders[sort]
+}
+
+// userIDs - converts the ID, login and name of each user to numeric MVA values,
+// so that any of them matches
+func userIDs(users []tracker.IndexedUser) []int64 {
+	var values []string
+	for _, u := range users {
+		values = append(values, u.ID, u.Login, u.Name)
+	}
+	return userHashes(values)
+}
+
+// userHashes - hashes user identifiers ignoring case
+func userHashes(values []string) []int64 {
+	var hashes []int64
+	for _, v := range uniqueValues(values) {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			hashes = append(hashes, hashString(v))
+		}
+	}
+	return hashes
+}
+
+// userCondition - matches documents with any of the users given by ID, login or name
+// in the column of user hashes
+func userCondition(column string, users []string) string {
+	hashes := userHashes(users)
+	values := make([]string, len(hashes))
+	for i, h := range hashes {
+		values[i] = fmt.Sprintf("%d", h)
+	}
+	if len(values) == 0 {
+		return "id < 0"
+	}
+	return fmt.Sprintf("ANY(%s) IN (%s)", column, strings.Join(values, ", "))
+}
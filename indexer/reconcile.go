@@ -0,0 +1,118 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+const (
+	// DefaultMaxDeletePercent - share of indexed issues that may be removed without confirmation
+	DefaultMaxDeletePercent = 10
+	// removeBatchSize - issues removed per query
+	removeBatchSize = 500
+)
+
+// ReconcileOptions - reconciliation parameters
+type ReconcileOptions struct {
+	// MaxDeletePercent - largest share of indexed issues removed without confirmation
+	MaxDeletePercent int
+	// Confirmed - remove stale issues above the threshold too
+	Confirmed bool
+}
+
+// Reconciliation - result of comparing the index with the issues in Tracker
+type Reconciliation struct {
+	// Indexed - number of issues in the index
+	Indexed int `json:"indexed"`
+	// Stale - keys of indexed issues missing in Tracker: deleted, moved out of synced queues or inaccessible
+	Stale []string `json:"stale,omitempty"`
+	// Removed - number of removed stale issues
+	Removed int `json:"removed"`
+	// Blocked - stale issues exceed the threshold and were kept until confirmed
+	Blocked bool `json:"blocked"`
+}
+
+// Percent - share of stale issues among indexed ones
+func (r Reconciliation) Percent() float64 {
+	if r.Indexed == 0 {
+		return 0
+	}
+	return float64(len(r.Stale)) * 100 / float64(r.Indexed)
+}
+
+// IndexedKeys - returns keys of all indexed issues
+func (idx *Indexer) IndexedKeys(ctx context.Context) ([]string, error) {
+	var keys []string
+	for offset := 0; ; offset += scanPageSize {
+		rows, err := idx.queryRows(ctx, fmt.Sprintf(
+			`SELECT issue_key FROM %s ORDER BY issue_key ASC LIMIT %d, %d OPTION max_matches=%d`,
+			tableName, offset, scanPageSize, offset+scanPageSize))
+		if err != nil {
+			return nil, fmt.Errorf("scan issue keys: %w", err)
+		}
+		for _, row := range rows {
+			keys = append(keys, getStringFromMap(row, "issue_key"))
+		}
+		if len(rows) < scanPageSize {
+			return keys, nil
+		}
+	}
+}
+
+// Reconcile - removes indexed issues whose keys are not among the keys of a complete sync.
+// If more than the allowed share of issues would be removed, nothing is removed unless confirmed,
+// since an incomplete listing or lost access would otherwise empty the index
+func (idx *Indexer) Reconcile(ctx context.Context, keys []string, opts ReconcileOptions) (*Reconciliation, error) {
+	indexed, err := idx.IndexedKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	synced := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		synced[key] = true
+	}
+
+	result := &Reconciliation{Indexed: len(indexed)}
+	for _, key := range uniqueValues(indexed) {
+		if !synced[key] {
+			result.Stale = append(result.Stale, key)
+		}
+	}
+	sort.Strings(result.Stale)
+
+	if len(result.Stale) == 0 {
+		return result, nil
+	}
+	if result.Percent() > float64(opts.MaxDeletePercent) && !opts.Confirmed {
+		result.Blocked = true
+		return result, nil
+	}
+
+	if err := idx.RemoveIssues(ctx, result.Stale); err != nil {
+		return result, err
+	}
+	result.Removed = len(result.Stale)
+	return result, nil
+}
+
+// RemoveIssues - removes issues and rows of related tables by keys
+func (idx *Indexer) RemoveIssues(ctx context.Context, keys []string) error {
+	for start := 0; start < len(keys); start += removeBatchSize {
+		end := min(start+removeBatchSize, len(keys))
+		batch := FieldFilter{Values: keys[start:end]}
+
+		deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE %s`, tableName, batch.condition("issue_key"))
+		if _, err := idx.queryRows(ctx, deleteSQL); err != nil {
+			return fmt.Errorf("delete issues: %w", err)
+		}
+		for _, t := range keyTables {
+			deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE %s`, t.name, batch.condition(t.column))
+			if _, err := idx.queryRows(ctx, deleteSQL); err != nil {
+				return fmt.Errorf("delete %s of removed issues: %w", t.name, err)
+			}
+		}
+	}
+	return nil
+}
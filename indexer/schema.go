@@ -0,0 +1,336 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// column - table column definition
+type column struct {
+	name string
+	def  string
+}
+
+// table - table definition
+type table struct {
+	name    string
+	columns []column
+	options string
+}
+
+// Manticore CREATE TABLE syntax
+// TEXT - full-text search (TEXT STORED - stored only, not indexed)
+// STRING - exact match, filtering
+// BIGINT - numbers
+// TIMESTAMP - dates
+// MULTI, MULTI64 - arrays for MVA (multi-value attributes)
+// JSON - structured values
+var tables = []table{
+	{
+		name: tableName,
+		columns: []column{
+			{"issue_key", "STRING"},
+			{"url", "STRING"},
+			{"summary", "TEXT"},
+			{"description", "TEXT"},
+			{"comments_text", "TEXT"},
+			{"queue", "STRING"},
+			{"status", "STRING"},
+			{"status_name", "STRING"},
+			{"priority", "STRING"},
+			{"type", "STRING"},
+			{"resolution", "STRING"},
+			{"author", "STRING"},
+			{"author_name", "STRING"},
+			{"assignee", "STRING"},
+			{"assignee_name", "STRING"},
+			{"tags", "MULTI"},
+			{"created_at", "TIMESTAMP"},
+			{"updated_at", "TIMESTAMP"},
+			{"description_raw", "TEXT STORED"},
+			{"synced_at", "TIMESTAMP"},
+			{"code_text", "TEXT"},
+			{"refs", "JSON"},
+			{"parent_key", "STRING"},
+			{"epic_key", "STRING"},
+			{"ancestor_path", "STRING"},
+			{"ancestors", "MULTI64"},
+			{"status_since", "TIMESTAMP"},
+			{"attachment_names", "TEXT"},
+			{"attachments_text", "TEXT"},
+			{"worklog_text", "TEXT"},
+			{"checklist_text", "TEXT"},
+			{"custom_fields", "JSON"},
+			{"queue_name", "STRING"},
+			{"priority_name", "STRING"},
+			{"priority_order", "INTEGER"},
+			{"type_name", "STRING"},
+			{"resolution_name", "STRING"},
+			{"status_category", "STRING"},
+			{"deadline", "TIMESTAMP"},
+			{"start_date", "TIMESTAMP"},
+			{"end_date", "TIMESTAMP"},
+			{"components", "MULTI64"},
+			{"fix_versions", "MULTI64"},
+			{"affected_versions", "MULTI64"},
+			{"sprints", "MULTI64"},
+			{"planning", "JSON"},
+			{"follower_ids", "MULTI64"},
+			{"followers", "JSON"},
+			{"follower_count", "INTEGER"},
+			{"votes", "INTEGER"},
+			{"comment_count", "INTEGER"},
+			{"last_commented_at", "TIMESTAMP"},
+			{"resolved_at", "TIMESTAMP"},
+			{"issue_keys", "TEXT"},
+			{"key_ids", "MULTI64"},
+			{"previous_keys", "JSON"},
+			{"tracker_id", "STRING"},
+		},
+		options: "morphology='stem_en, stem_ru' html_strip='1'",
+	},
+	{
+		name: commentsTableName,
+		columns: []column{
+			{"issue_key", "STRING"},
+			{"author", "STRING"},
+			{"author_name", "STRING"},
+			{"text", "TEXT"},
+			{"created_at", "TIMESTAMP"},
+			{"updated_at", "TIMESTAMP"},
+		},
+		options: "morphology='stem_en, stem_ru'",
+	},
+	{
+		name: linksTableName,
+		columns: []column{
+			{"source_key", "STRING"},
+			{"target_key", "STRING"},
+			{"target_display", "STRING"},
+			{"relation", "STRING"},
+			{"link_type", "STRING"},
+			{"direction", "STRING"},
+		},
+	},
+	{
+		name: changesTableName,
+		columns: []column{
+			{"issue_key", "STRING"},
+			{"change_id", "STRING"},
+			{"change_seq", "BIGINT"},
+			{"change_type", "STRING"},
+			{"field", "STRING"},
+			{"field_name", "STRING"},
+			{"from_value", "STRING"},
+			{"from_display", "STRING"},
+			{"to_value", "STRING"},
+			{"to_display", "STRING"},
+			{"author", "STRING"},
+			{"author_name", "STRING"},
+			{"changed_at", "TIMESTAMP"},
+		},
+	},
+	{
+		name: attachmentsTableName,
+		columns: []column{
+			{"issue_key", "STRING"},
+			{"attachment_id", "STRING"},
+			{"name", "TEXT"},
+			{"file_name", "STRING"},
+			{"mimetype", "STRING"},
+			{"size", "BIGINT"},
+			{"author", "STRING"},
+			{"author_name", "STRING"},
+			{"created_at", "TIMESTAMP"},
+			{"content_hash", "STRING"},
+			{"text", "TEXT"},
+		},
+		options: "morphology='stem_en, stem_ru'",
+	},
+	{
+		name: worklogTableName,
+		columns: []column{
+			{"issue_key", "STRING"},
+			{"queue", "STRING"},
+			{"author", "STRING"},
+			{"author_name", "STRING"},
+			{"comment", "TEXT"},
+			{"start_at", "TIMESTAMP"},
+			{"week_start", "TIMESTAMP"},
+			{"duration", "BIGINT"},
+			{"created_at", "TIMESTAMP"},
+		},
+		options: "morphology='stem_en, stem_ru'",
+	},
+	{
+		name: checklistTableName,
+		columns: []column{
+			{"issue_key", "STRING"},
+			{"item_id", "STRING"},
+			{"position", "INTEGER"},
+			{"text", "TEXT"},
+			{"checked", "INTEGER"},
+			{"assignee", "STRING"},
+			{"assignee_name", "STRING"},
+			{"deadline", "TIMESTAMP"},
+		},
+		options: "morphology='stem_en, stem_ru'",
+	},
+	{
+		name: dictionariesTableName,
+		columns: []column{
+			{"kind", "STRING"},
+			{"dict_key", "STRING"},
+			{"tracker_id", "STRING"},
+			{"names", "JSON"},
+			{"sort_order", "INTEGER"},
+			{"category", "STRING"},
+			{"parent", "STRING"},
+			{"status", "STRING"},
+			{"start_date", "TIMESTAMP"},
+			{"end_date", "TIMESTAMP"},
+		},
+	},
+	{
+		name: summonsTableName,
+		columns: []column{
+			{"issue_key", "STRING"},
+			{"user_id", "STRING"},
+			{"user_login", "STRING"},
+			{"user_name", "STRING"},
+			{"user_ids", "MULTI64"},
+			{"kind", "STRING"},
+			{"comment_id", "BIGINT"},
+			{"author", "STRING"},
+			{"author_name", "STRING"},
+			{"summoned_at", "TIMESTAMP"},
+			{"status_category", "STRING"},
+		},
+	},
+	{
+		name: checkpointTableName,
+		columns: []column{
+			{"issue_key", "STRING"},
+			{"updated_at", "TIMESTAMP"},
+			{"processed_at", "TIMESTAMP"},
+		},
+	},
+}
+
+// createTable - creates the table if it doesn't exist and adds columns missing in older versions
+func (idx *Indexer) createTable(ctx context.Context, t table) error {
+	defs := []string{"id BIGINT"}
+	for _, c := range t.columns {
+		defs = append(defs, c.name+" "+c.def)
+	}
+
+	createSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t\t%s\n\t) %s",
+		t.name, strings.Join(defs, ",\n\t\t"), t.options)
+
+	if _, err := idx.queryRows(ctx, createSQL); err != nil {
+		return fmt.Errorf("create table %s: %w", t.name, err)
+	}
+
+	rows, err := idx.queryRows(ctx, "DESCRIBE "+t.name)
+	if err != nil {
+		return fmt.Errorf("describe table %s: %w", t.name, err)
+	}
+
+	existing := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		existing[getStringFromMap(row, "Field")] = true
+	}
+
+	for _, c := range t.columns {
+		if existing[c.name] {
+			continue
+		}
+		alterSQL := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", t.name, c.name, c.def)
+		if _, err := idx.queryRows(ctx, alterSQL); err != nil {
+			return fmt.Errorf("add column %s.%s: %w", t.name, c.name, err)
+		}
+		log.Printf("Added column '%s' to table '%s'", c.name, t.name)
+	}
+
+	log.Printf("Table '%s' created/verified", t.name)
+	return nil
+}
+
+// document - column values of a document for REPLACE statements
+type document struct {
+	columns []string
+	values  []string
+}
+
+// str - adds a string value
+func (d *document) str(column, value string) {
+	d.columns = append(d.columns, column)
+	d.values = append(d.values, "'"+escapeSQL(value)+"'")
+}
+
+// int - adds an integer value
+func (d *document) int(column string, value int64) {
+	d.columns = append(d.columns, column)
+	d.values = append(d.values, fmt.Sprintf("%d", value))
+}
+
+// float - adds a floating point value
+func (d *document) float(column string, value float64) {
+	d.columns = append(d.columns, column)
+	d.values = append(d.values, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// multi - adds a multi-value attribute
+func (d *document) multi(column string, values []int64) {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%d", v)
+	}
+	d.columns = append(d.columns, column)
+	d.values = append(d.values, "("+strings.Join(parts, ",")+")")
+}
+
+// time - adds a timestamp value, zero time is stored as 0
+func (d *document) time(column string, value time.Time) {
+	if value.IsZero() {
+		d.int(column, 0)
+		return
+	}
+	d.int(column, value.Unix())
+}
+
+// json - adds a JSON value
+func (d *document) json(column string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		data = []byte("{}")
+	}
+	d.str(column, string(data))
+}
+
+// set - replaces values of the columns also present in the other document
+func (d *document) set(other document) {
+	for i, column := range other.columns {
+		for j := range d.columns {
+			if d.columns[j] == column {
+				d.values[j] = other.values[i]
+			}
+		}
+	}
+}
+
+// replaceSQL - builds a REPLACE statement for documents with the same set of columns
+func replaceSQL(tableName string, docs []document) string {
+	values := make([]string, len(docs))
+	for i, d := range docs {
+		values[i] = "(" + strings.Join(d.values, ", ") + ")"
+	}
+
+	return fmt.Sprintf("REPLACE INTO %s (%s) VALUES %s",
+		tableName, strings.Join(docs[0].columns, ", "), strings.Join(values, ", "))
+}
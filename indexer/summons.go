@@ -0,0 +1,207 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"ytbs/tracker"
+)
+
+// maxInboxItems - upper bound of summons listed in an inbox
+const maxInboxItems = 1000
+
+// Inbox orders
+const (
+	// InboxOldest - longest waiting summons first
+	InboxOldest = "oldest"
+	// InboxNewest - latest summons first
+	InboxNewest = "newest"
+)
+
+// indexSummons - replaces stored summons of an issue awaiting reply. The status category
+// of the issue is stored with them, so that the inbox skips finished issues before its limit
+func (idx *Indexer) indexSummons(ctx context.Context, issue tracker.IndexedIssue, summons []tracker.IndexedSummons) error {
+	issueKey := issue.Key
+	deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE issue_key = '%s'`, summonsTableName, escapeSQL(issueKey))
+	if _, err := idx.queryRows(ctx, deleteSQL); err != nil {
+		return fmt.Errorf("delete summons of %s: %w", issueKey, err)
+	}
+
+	if len(summons) == 0 {
+		return nil
+	}
+
+	docs := make([]document, 0, len(summons))
+	for _, s := range summons {
+		var doc document
+		doc.int("id", documentID(issueKey+"|"+s.User.ID+"|"+strings.ToLower(s.User.Login)))
+		doc.str("issue_key", issueKey)
+		doc.str("user_id", s.User.ID)
+		doc.str("user_login", s.User.Login)
+		doc.str("user_name", s.User.Name)
+		doc.multi("user_ids", userIDs([]tracker.IndexedUser{s.User}))
+		doc.str("kind", s.Kind)
+		doc.int("comment_id", s.CommentID)
+		doc.str("author", s.Author)
+		doc.str("author_name", s.AuthorName)
+		doc.time("summoned_at", s.SummonedAt)
+		doc.str("status_category", issue.StatusCategory)
+		docs = append(docs, doc)
+	}
+
+	if _, err := idx.queryRows(ctx, replaceSQL(summonsTableName, docs)); err != nil {
+		return fmt.Errorf("replace summons of %s: %w", issueKey, err)
+	}
+	return nil
+}
+
+// GetSummons - returns stored summons of an issue awaiting reply, longest waiting first
+func (idx *Indexer) GetSummons(ctx context.Context, issueKey string) ([]tracker.IndexedSummons, error) {
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT * FROM %s WHERE issue_key = '%s' ORDER BY summoned_at ASC LIMIT 1000`,
+		summonsTableName, escapeSQL(issueKey)))
+	if err != nil {
+		return nil, fmt.Errorf("get summons of %s: %w", issueKey, err)
+	}
+
+	summons := make([]tracker.IndexedSummons, 0, len(rows))
+	for _, row := range rows {
+		summons = append(summons, tracker.IndexedSummons{
+			User: tracker.IndexedUser{
+				ID:    getStringFromMap(row, "user_id"),
+				Login: getStringFromMap(row, "user_login"),
+				Name:  getStringFromMap(row, "user_name"),
+			},
+			Kind:       getStringFromMap(row, "kind"),
+			CommentID:  int64(getIntFromMap(row, "comment_id")),
+			Author:     getStringFromMap(row, "author"),
+			AuthorName: getStringFromMap(row, "author_name"),
+			SummonedAt: getTimeFromMap(row, "summoned_at"),
+		})
+	}
+	return summons, nil
+}
+
+// InboxOptions - inbox parameters
+type InboxOptions struct {
+	// User - ID, login or name of the summoned user
+	User string
+	// Order - oldest or newest summons first
+	Order string
+	// Done - include issues in the done status category
+	Done bool
+}
+
+// ParseInboxOptions - reads inbox parameters user, order and done; the longest waiting
+// summons of unfinished issues come first by default
+func ParseInboxOptions(values url.Values) InboxOptions {
+	opts := InboxOptions{
+		User:  strings.TrimSpace(values.Get("user")),
+		Order: values.Get("order"),
+		Done:  values.Get("done") == "1",
+	}
+	if opts.Order != InboxNewest {
+		opts.Order = InboxOldest
+	}
+	return opts
+}
+
+// Query - converts inbox parameters back to URL query parameters
+func (o InboxOptions) Query() url.Values {
+	values := url.Values{}
+	if o.User != "" {
+		values.Set("user", o.User)
+	}
+	values.Set("order", o.Order)
+	if o.Done {
+		values.Set("done", "1")
+	}
+	return values
+}
+
+// InboxItem - issue where the user was summoned or mentioned and has not commented since
+type InboxItem struct {
+	Key            string `json:"key"`
+	URL            string `json:"url"`
+	Summary        string `json:"summary"`
+	StatusName     string `json:"status_name"`
+	StatusCategory string `json:"status_category,omitempty"`
+	AssigneeName   string `json:"assignee_name"`
+	// Kind - summon or mention
+	Kind       string    `json:"kind"`
+	CommentID  int64     `json:"comment_id"`
+	AuthorName string    `json:"author_name"`
+	SummonedAt time.Time `json:"summoned_at"`
+}
+
+// Inbox - returns issues awaiting reply from the user, ordered by the age of the summons
+func (idx *Indexer) Inbox(ctx context.Context, opts InboxOptions) ([]InboxItem, error) {
+	items := []InboxItem{}
+	if opts.User == "" {
+		return items, nil
+	}
+
+	order := "ASC"
+	if opts.Order == InboxNewest {
+		order = "DESC"
+	}
+
+	conditions := []string{userCondition("user_ids", []string{opts.User})}
+	if !opts.Done {
+		conditions = append(conditions, fmt.Sprintf("status_category != '%s'", tracker.CategoryDone))
+	}
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT issue_key, kind, comment_id, author_name, summoned_at FROM %s
+		 WHERE %s ORDER BY summoned_at %s LIMIT %d OPTION max_matches=%d`,
+		summonsTableName, strings.Join(conditions, " AND "), order, maxInboxItems, maxInboxItems))
+	if err != nil {
+		return nil, fmt.Errorf("inbox of %s: %w", opts.User, err)
+	}
+	if len(rows) == 0 {
+		return items, nil
+	}
+
+	keys := make([]string, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, getStringFromMap(row, "issue_key"))
+	}
+	issueRows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT issue_key, url, summary, status_name, status_category, assignee_name FROM %s
+		 WHERE %s LIMIT %d OPTION max_matches=%d`,
+		tableName, keysCondition(uniqueValues(keys)), maxInboxItems, maxInboxItems))
+	if err != nil {
+		return nil, fmt.Errorf("inbox issues of %s: %w", opts.User, err)
+	}
+	issues := make(map[string]map[string]interface{}, len(issueRows))
+	for _, row := range issueRows {
+		issues[getStringFromMap(row, "issue_key")] = row
+	}
+
+	// an issue is listed once, also if the user is matched by several identifiers
+	seen := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		key := getStringFromMap(row, "issue_key")
+		issue, ok := issues[key]
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		items = append(items, InboxItem{
+			Key:            key,
+			URL:            getStringFromMap(issue, "url"),
+			Summary:        getStringFromMap(issue, "summary"),
+			StatusName:     getStringFromMap(issue, "status_name"),
+			StatusCategory: getStringFromMap(issue, "status_category"),
+			AssigneeName:   getStringFromMap(issue, "assignee_name"),
+			Kind:           getStringFromMap(row, "kind"),
+			CommentID:      int64(getIntFromMap(row, "comment_id")),
+			AuthorName:     getStringFromMap(row, "author_name"),
+			SummonedAt:     getTimeFromMap(row, "summoned_at"),
+		})
+	}
+
+	return items, nil
+}
@@ -0,0 +1,276 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"ytbs/tracker"
+)
+
+const (
+	// maxReportIssues - upper bound of issues matched by report filters
+	maxReportIssues = 100000
+	// maxReportRows - upper bound of rows in a report
+	maxReportRows = 1000
+)
+
+// worklogDimensions - report dimensions and their worklog table columns
+var worklogDimensions = map[string]string{
+	"person": "author_name",
+	"queue":  "queue",
+	"issue":  "issue_key",
+	"week":   "week_start",
+}
+
+// IsWorklogDimension - checks if the worklog report can be grouped by the dimension
+func IsWorklogDimension(by string) bool {
+	_, ok := worklogDimensions[by]
+	return ok
+}
+
+// indexWorklog - replaces stored worklog of an issue
+func (idx *Indexer) indexWorklog(ctx context.Context, issue tracker.IndexedIssue) error {
+	deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE issue_key = '%s'`, worklogTableName, escapeSQL(issue.Key))
+	if _, err := idx.queryRows(ctx, deleteSQL); err != nil {
+		return fmt.Errorf("delete worklog of %s: %w", issue.Key, err)
+	}
+
+	if len(issue.Worklog) == 0 {
+		return nil
+	}
+
+	docs := make([]document, 0, len(issue.Worklog))
+	for _, w := range issue.Worklog {
+		var doc document
+		doc.int("id", w.ID)
+		doc.str("issue_key", issue.Key)
+		doc.str("queue", issue.Queue)
+		doc.str("author", w.Author)
+		doc.str("author_name", w.AuthorName)
+		doc.str("comment", w.Comment)
+		doc.time("start_at", w.Start)
+		doc.time("week_start", weekStart(w.Start))
+		doc.int("duration", w.Seconds)
+		doc.time("created_at", w.CreatedAt)
+		docs = append(docs, doc)
+	}
+
+	if _, err := idx.queryRows(ctx, replaceSQL(worklogTableName, docs)); err != nil {
+		return fmt.Errorf("replace worklog of %s: %w", issue.Key, err)
+	}
+	return nil
+}
+
+// weekStart - returns the beginning of the Monday of the week
+func weekStart(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	t = t.Local()
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.Local)
+}
+
+// GetWorklog - returns stored worklog of an issue in chronological order
+func (idx *Indexer) GetWorklog(ctx context.Context, issueKey string) ([]tracker.IndexedWorklog, error) {
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT * FROM %s WHERE issue_key = '%s' ORDER BY start_at ASC LIMIT 1000`,
+		worklogTableName, escapeSQL(issueKey)))
+	if err != nil {
+		return nil, fmt.Errorf("get worklog of %s: %w", issueKey, err)
+	}
+
+	worklog := make([]tracker.IndexedWorklog, 0, len(rows))
+	for _, row := range rows {
+		worklog = append(worklog, tracker.IndexedWorklog{
+			ID:         int64(getIntFromMap(row, "id")),
+			Author:     getStringFromMap(row, "author"),
+			AuthorName: getStringFromMap(row, "author_name"),
+			Comment:    getStringFromMap(row, "comment"),
+			Start:      getTimeFromMap(row, "start_at"),
+			Seconds:    int64(getIntFromMap(row, "duration")),
+			CreatedAt:  getTimeFromMap(row, "created_at"),
+		})
+	}
+	return worklog, nil
+}
+
+// WorklogOptions - worklog report parameters
+type WorklogOptions struct {
+	// By - report dimension: person, queue, issue or week
+	By string
+	// From, To - period of work start times, open if zero
+	From time.Time
+	To   time.Time
+}
+
+// ParseWorklogOptions - reads report parameters by, from and to; the report is grouped by person by default
+func ParseWorklogOptions(values url.Values) WorklogOptions {
+	opts := WorklogOptions{
+		By:   values.Get("by"),
+		From: parseDate(values.Get("from"), false),
+		To:   parseDate(values.Get("to"), true),
+	}
+	if opts.By == "" {
+		opts.By = "person"
+	}
+	return opts
+}
+
+// Query - converts report parameters back to URL query parameters
+func (o WorklogOptions) Query() url.Values {
+	values := url.Values{}
+	values.Set("by", o.By)
+	if !o.From.IsZero() {
+		values.Set("from", o.From.Format(dateLayout))
+	}
+	if !o.To.IsZero() {
+		values.Set("to", o.To.Format(dateLayout))
+	}
+	return values
+}
+
+// WorklogRow - logged time of one dimension value
+type WorklogRow struct {
+	Value string `json:"value"`
+	// Label - display name of the value, e.g. issue summary
+	Label   string  `json:"label,omitempty"`
+	Hours   float64 `json:"hours"`
+	Entries int     `json:"entries"`
+}
+
+// WorklogReport - logged time grouped by a dimension
+type WorklogReport struct {
+	By         string       `json:"by"`
+	From       *time.Time   `json:"from,omitempty"`
+	To         *time.Time   `json:"to,omitempty"`
+	TotalHours float64      `json:"total_hours"`
+	Entries    int          `json:"entries"`
+	Rows       []WorklogRow `json:"rows"`
+}
+
+// WorklogReport - sums time logged on issues matching the query and filters
+func (idx *Indexer) WorklogReport(ctx context.Context, query string, filters SearchFilters, opts WorklogOptions) (*WorklogReport, error) {
+	column, ok := worklogDimensions[opts.By]
+	if !ok {
+		return nil, fmt.Errorf("unsupported report dimension %q", opts.By)
+	}
+
+	report := &WorklogReport{By: opts.By, Rows: []WorklogRow{}}
+	if !opts.From.IsZero() {
+		report.From = &opts.From
+	}
+	if !opts.To.IsZero() {
+		report.To = &opts.To
+	}
+
+	var conditions []string
+	if query != "" || !filters.IsEmpty() {
+		keys, err := idx.matchingKeys(ctx, query, filters)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			return report, nil
+		}
+		conditions = append(conditions, FieldFilter{Values: keys}.condition("issue_key"))
+	}
+	if !opts.From.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("start_at >= %d", opts.From.Unix()))
+	}
+	if !opts.To.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("start_at <= %d", opts.To.Unix()))
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT %s, SUM(duration) as seconds, COUNT(*) as entries FROM %s %s
+		 GROUP BY %s ORDER BY seconds DESC LIMIT %d OPTION max_matches=%d`,
+		column, worklogTableName, whereClause, column, maxReportRows, maxReportRows))
+	if err != nil {
+		return nil, fmt.Errorf("worklog report: %w", err)
+	}
+
+	var totalSeconds int64
+	for _, row := range rows {
+		seconds := int64(getIntFromMap(row, "seconds"))
+		r := WorklogRow{
+			Value:   getStringFromMap(row, column),
+			Hours:   hours(seconds),
+			Entries: getIntFromMap(row, "entries"),
+		}
+		if opts.By == "week" {
+			r.Value = getTimeFromMap(row, column).Format(dateLayout)
+		}
+		totalSeconds += seconds
+		report.Entries += r.Entries
+		report.Rows = append(report.Rows, r)
+	}
+	report.TotalHours = hours(totalSeconds)
+
+	switch opts.By {
+	case "week":
+		sort.Slice(report.Rows, func(i, j int) bool { return report.Rows[i].Value < report.Rows[j].Value })
+	case "issue":
+		if err := idx.labelIssues(ctx, report.Rows); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// matchingKeys - returns keys of issues matching the query and filters
+func (idx *Indexer) matchingKeys(ctx context.Context, query string, filters SearchFilters) ([]string, error) {
+	whereClause, err := idx.buildWhere(ctx, query, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := idx.queryRows(ctx, fmt.Sprintf(
+		`SELECT issue_key FROM %s %s LIMIT %d OPTION max_matches=%d`,
+		tableName, whereClause, maxReportIssues, maxReportIssues))
+	if err != nil {
+		return nil, fmt.Errorf("matching issues: %w", err)
+	}
+
+	keys := make([]string, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, getStringFromMap(row, "issue_key"))
+	}
+	return keys, nil
+}
+
+// labelIssues - sets issue summaries as row labels
+func (idx *Indexer) labelIssues(ctx context.Context, rows []WorklogRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(rows))
+	for i, r := range rows {
+		keys[i] = r.Value
+	}
+	nodes, err := idx.graphNodes(ctx, keys)
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		rows[i].Label = nodes[rows[i].Value].Summary
+	}
+	return nil
+}
+
+// hours - converts seconds to hours rounded to hundredths
+func hours(seconds int64) float64 {
+	return math.Round(float64(seconds)/36) / 100
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -21,12 +22,27 @@ var (
 	trackerToken string = os.Getenv("TRACKER_OAUTH_TOKEN")
 	trackerOrgID string = os.Getenv("TRACKER_CLOUD_ORG_ID")
 
+	attachmentsMaxSize = envInt("ATTACHMENTS_MAX_SIZE", 0)
+	attachmentsWorkers = envInt("ATTACHMENTS_WORKERS", 2)
+
+	maxDeletePercent = envInt("MAX_DELETE_PERCENT", indexer.DefaultMaxDeletePercent)
+
+	syncRecentDays      = envInt("SYNC_RECENT_DAYS", 30)
+	syncBackfillWorkers = envInt("SYNC_BACKFILL_WORKERS", 2)
+	syncFetchWorkers    = envInt("SYNC_FETCH_WORKERS", 4)
+
+	customFieldsSpec = os.Getenv("CUSTOM_FIELDS")
+	trackerLanguage  = os.Getenv("TRACKER_LANGUAGE")
+
 	helpText = `Yandex Tracker Better Search
 
 Usage:
   -serve              Run web server with UI and periodic sync
   -sync               Run one-time sync from Tracker
+  -confirm-deletions  With -sync, remove issues missing in Tracker above MAX_DELETE_PERCENT
   -search TEXT        Search for issues (CLI mode)
+  -verify-ids         Check the index for issues stored under colliding document IDs
+  -repair             With -verify-ids, move affected issues to collision-free IDs
   -h, -help           Show this message
 
 Server options:
@@ -36,13 +52,29 @@ Server options:
 Environment variables:
   TRACKER_OAUTH_TOKEN   - OAuth token for Yandex Tracker
   TRACKER_CLOUD_ORG_ID  - Cloud Organization ID
-  MANTICORE_URL         - Manticore Search URL (default: http://localhost:9308)`
+  MANTICORE_URL         - Manticore Search URL (default: http://localhost:9308)
+  ATTACHMENTS_MAX_SIZE  - Download attachments up to this size in bytes to index their text
+                          (default: 0, only names are indexed)
+  ATTACHMENTS_WORKERS   - Concurrent attachment downloads (default: 2)
+  CUSTOM_FIELDS         - Local and extra fields to index as id:type[:name], comma separated;
+                          types: text, string, number, date
+                          (e.g. storyPoints:number:Story points,customer:string)
+  TRACKER_LANGUAGE      - Language of priority, status, type and queue names (default: ru)
+  MAX_DELETE_PERCENT    - Largest share of indexed issues missing in Tracker removed after a sync
+                          without confirmation (default: 10)
+  SYNC_RECENT_DAYS      - Issues updated within this many days are synced and searchable first,
+                          older ones are backfilled afterwards; 0 to sync all at once (default: 30)
+  SYNC_BACKFILL_WORKERS - Concurrent detail loaders while backfilling older issues (default: 2)
+  SYNC_FETCH_WORKERS    - Concurrent scrolls listing issues, one per queue or creation year (default: 4)`
 )
 
 func main() {
 	serveFlag := flag.Bool("serve", false, "Run web server with periodic sync")
 	syncFlag := flag.Bool("sync", false, "Run one-time sync from Tracker")
+	confirmFlag := flag.Bool("confirm-deletions", false, "Remove issues missing in Tracker above the threshold")
 	searchFlag := flag.String("search", "", "Search query (CLI mode)")
+	verifyFlag := flag.Bool("verify-ids", false, "Check document IDs for collisions")
+	repairFlag := flag.Bool("repair", false, "Repair document IDs found by -verify-ids")
 	addrFlag := flag.String("addr", ":8080", "HTTP server address")
 	intervalFlag := flag.Duration("interval", 15*time.Minute, "Sync interval")
 	helpFlag := flag.Bool("h", false, "Show help")
@@ -67,7 +99,14 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
+	customFields, err := indexer.ParseCustomFields(customFieldsSpec)
+	if err != nil {
+		log.Fatalf("Invalid CUSTOM_FIELDS: %v", err)
+	}
+	indexer.SetCustomFields(customFields)
+
 	idx := indexer.NewIndexer(manticoreURL)
+	idx.SetLanguage(trackerLanguage)
 
 	if err := idx.CreateTable(ctx); err != nil {
 		log.Fatalf("Failed to create table: %v", err)
@@ -81,7 +120,7 @@ func main() {
 
 	// One-time sync mode
 	if *syncFlag {
-		runSync(ctx, idx)
+		runSync(ctx, idx, *confirmFlag)
 		return
 	}
 
@@ -91,13 +130,20 @@ func main() {
 		return
 	}
 
+	// Document ID verification mode
+	if *verifyFlag {
+		runVerifyIDs(ctx, idx, *repairFlag)
+		return
+	}
+
 	fmt.Println(helpText)
 }
 
 func runServer(ctx context.Context, idx *indexer.Indexer, addr string, interval time.Duration) {
 	client := tracker.NewClient(trackerToken, trackerOrgID)
 
-	syncMgr := sync.NewManager(client, idx, nil, 5, interval)
+	syncMgr := sync.NewManager(client, idx, syncOptions(), interval)
+	syncMgr.SetMaxDeletePercent(maxDeletePercent)
 
 	go syncMgr.Start(ctx)
 
@@ -112,16 +158,52 @@ func runServer(ctx context.Context, idx *indexer.Indexer, addr string, interval
 	}
 }
 
-func runSync(ctx context.Context, idx *indexer.Indexer) {
+// syncOptions - sync parameters from the environment
+func syncOptions() tracker.SyncOptions {
+	return tracker.SyncOptions{
+		// specify queues to sync, or nil/empty for all accessible
+		// Queues: []string{"MYQUEUE", "ANOTHER"},
+		Workers:         5,
+		RecentDays:      syncRecentDays,
+		BackfillWorkers: syncBackfillWorkers,
+		FetchWorkers:    syncFetchWorkers,
+		Attachments: tracker.AttachmentOptions{
+			MaxSize: int64(attachmentsMaxSize),
+			Workers: attachmentsWorkers,
+		},
+	}
+}
+
+// envInt - reads an integer environment variable, def if it is unset or invalid
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using %d", name, value, def)
+		return def
+	}
+	return n
+}
+
+func runSync(ctx context.Context, idx *indexer.Indexer, confirmDeletions bool) {
 	client := tracker.NewClient(trackerToken, trackerOrgID)
 
-	// Options: specify queues to sync, or nil/empty for all accessible
-	// queues := []string{"MYQUEUE", "ANOTHER"}
-	var queues []string
+	options := idx.SyncOptions(syncOptions())
+
+	log.Println("Syncing dictionaries...")
+	if entries, err := client.FetchDictionaries(ctx); err != nil {
+		log.Printf("Warning: failed to fetch dictionaries: %v", err)
+	} else if err := idx.IndexDictionaries(ctx, entries); err != nil {
+		log.Printf("Warning: failed to index dictionaries: %v", err)
+	}
 
 	log.Println("Starting initial sync from Yandex Tracker...")
 
-	issues, result, err := client.InitialSync(ctx, queues, 5)
+	// issues are indexed in batches, so an interrupted sync resumes from the checkpoint
+	_, result, err := client.InitialSync(ctx, options)
 	if err != nil {
 		log.Fatalf("Initial sync failed: %v", err)
 	}
@@ -129,10 +211,32 @@ func runSync(ctx context.Context, idx *indexer.Indexer) {
 	log.Printf("Fetched from Tracker:")
 	log.Printf("  - Issues: %d", result.TotalIssues)
 	log.Printf("  - Comments: %d", result.TotalComments)
+	log.Printf("  - New changes: %d", result.TotalChanges)
+	log.Printf("  - Attachments: %d (%d with text)", result.TotalAttachments, result.ExtractedAttachments)
+	log.Printf("  - Worklog entries: %d", result.TotalWorklog)
+	log.Printf("  - Resumed from: %d", result.Resumed)
 	log.Printf("  - Errors: %d", len(result.Errors))
+	for _, p := range result.Partitions {
+		log.Printf("  - Partition %s: %d issues", p.Name, p.Fetched)
+	}
 
-	if err := idx.IndexIssues(ctx, issues); err != nil {
-		log.Fatalf("Indexing failed: %v", err)
+	rec, err := idx.Reconcile(ctx, result.Keys, indexer.ReconcileOptions{
+		MaxDeletePercent: maxDeletePercent,
+		Confirmed:        confirmDeletions,
+	})
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+	switch {
+	case rec.Blocked:
+		log.Printf("Warning: %d of %d indexed issues (%.0f%%) are missing in Tracker, above the %d%% threshold; run with -confirm-deletions to remove them",
+			len(rec.Stale), rec.Indexed, rec.Percent(), maxDeletePercent)
+	case rec.Removed > 0:
+		log.Printf("Removed %d issues missing in Tracker", rec.Removed)
+	}
+
+	if err := idx.ClearCheckpoint(ctx); err != nil {
+		log.Printf("Warning: %v", err)
 	}
 
 	log.Println("Sync completed successfully!")
@@ -162,3 +266,27 @@ func runSearch(ctx context.Context, idx *indexer.Indexer, query string) {
 		log.Println()
 	}
 }
+
+func runVerifyIDs(ctx context.Context, idx *indexer.Indexer, repair bool) {
+	report, err := idx.VerifyIDs(ctx, repair)
+	if err != nil {
+		log.Fatalf("Verification failed: %v", err)
+	}
+
+	log.Printf("Checked %d documents", report.Documents)
+	for _, key := range report.Duplicates {
+		log.Printf("  [%s] stored in several documents", key)
+	}
+	for _, key := range report.Legacy {
+		log.Printf("  [%s] legacy document ID, may collide", key)
+	}
+
+	switch {
+	case report.OK():
+		log.Println("No collisions found")
+	case repair:
+		log.Printf("Repaired %d issues", len(report.Repaired))
+	default:
+		log.Println("Run with -repair to move affected issues to collision-free IDs")
+	}
+}
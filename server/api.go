@@ -0,0 +1,365 @@
+package server
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ytbs/indexer"
+	"ytbs/sync"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// APIError - error body of the JSON API
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorResponse - JSON API error envelope
+type errorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// APIHandler - returns a handler of the JSON API routes only, e.g. for in-process tests
+func (s *Server) APIHandler() http.Handler {
+	mux := http.NewServeMux()
+	s.registerAPI(mux)
+	return mux
+}
+
+// registerAPI - registers JSON API v1 routes
+func (s *Server) registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/search", s.apiSearch)
+	mux.HandleFunc("GET /api/v1/issues/{key}", s.apiIssue)
+	mux.HandleFunc("GET /api/v1/issues/{key}/graph", s.apiIssueGraph)
+	mux.HandleFunc("GET /api/v1/issues/{key}/rollup", s.apiIssueRollup)
+	mux.HandleFunc("GET /api/v1/filters", s.apiFilters)
+	mux.HandleFunc("GET /api/v1/dictionaries", s.apiDictionaries)
+	mux.HandleFunc("GET /api/v1/worklog/report", s.apiWorklogReport)
+	mux.HandleFunc("GET /api/v1/calendar.ics", s.apiCalendar)
+	mux.HandleFunc("GET /api/v1/metrics/flow", s.apiFlowMetrics)
+	mux.HandleFunc("GET /api/v1/inbox", s.apiInbox)
+	mux.HandleFunc("GET /api/v1/sync", s.apiSyncStatus)
+	mux.HandleFunc("POST /api/v1/sync", s.apiSyncTrigger)
+	mux.HandleFunc("DELETE /api/v1/sync", s.apiSyncCancel)
+	mux.HandleFunc("GET /api/v1/sync/logs", s.apiSyncLogs)
+	mux.HandleFunc("POST /api/v1/sync/deletions", s.apiSyncConfirmDeletions)
+	mux.HandleFunc("GET /api/v1/openapi.json", s.apiSpec)
+	mux.HandleFunc("/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "unknown API endpoint")
+	})
+}
+
+// writeJSON - writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("JSON encode error: %v", err)
+	}
+}
+
+// writeError - writes a JSON API error
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorResponse{Error: APIError{Code: code, Message: message}})
+}
+
+// queryInt - reads a non-negative integer query parameter
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, errors.New("parameter " + name + " must be a non-negative integer")
+	}
+	return n, nil
+}
+
+// queryList - reads a list query parameter given as repeated values
+func queryList(r *http.Request, name string) []string {
+	var list []string
+	for _, v := range r.URL.Query()[name] {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// apiSearch - search with filters, pagination and facets
+func (s *Server) apiSearch(w http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	limit, err := queryInt(r, "limit", 20)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	facets := queryList(r, "facets")
+	for _, f := range facets {
+		if !indexer.IsFacetable(f) {
+			writeError(w, http.StatusBadRequest, "bad_request", "unsupported facet field: "+f)
+			return
+		}
+	}
+
+	query := r.URL.Query().Get("q")
+	filters := indexer.ParseSearchFilters(r.URL.Query())
+
+	page, err := s.indexer.SearchPaged(r.Context(), query, filters, offset, limit, facets)
+	if err != nil {
+		log.Printf("API search error: %v", err)
+		writeError(w, http.StatusInternalServerError, "search_failed", err.Error())
+		return
+	}
+	if page.Results == nil {
+		page.Results = []indexer.SearchResult{}
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// apiIssue - indexed issue by key
+func (s *Server) apiIssue(w http.ResponseWriter, r *http.Request) {
+	issue, err := s.indexer.GetIssue(r.Context(), r.PathValue("key"))
+	if errors.Is(err, indexer.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, issue)
+}
+
+// apiIssueGraph - link graph around an issue
+func (s *Server) apiIssueGraph(w http.ResponseWriter, r *http.Request) {
+	depth, err := queryInt(r, "depth", 1)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	if depth < 1 || depth > indexer.MaxGraphDepth {
+		writeError(w, http.StatusBadRequest, "bad_request",
+			fmt.Sprintf("depth must be between 1 and %d", indexer.MaxGraphDepth))
+		return
+	}
+
+	key := r.PathValue("key")
+	graph, err := s.indexer.LinkGraph(r.Context(), key, depth)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	if !graph.Nodes[0].Indexed && len(graph.Edges) == 0 {
+		writeError(w, http.StatusNotFound, "not_found", "issue not found: "+key)
+		return
+	}
+	if graph.Edges == nil {
+		graph.Edges = []indexer.GraphEdge{}
+	}
+
+	writeJSON(w, http.StatusOK, graph)
+}
+
+// apiIssueRollup - descendant counts per status of an epic or parent issue
+func (s *Server) apiIssueRollup(w http.ResponseWriter, r *http.Request) {
+	rollup, err := s.indexer.GetRollup(r.Context(), r.PathValue("key"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	if rollup.Statuses == nil {
+		rollup.Statuses = []indexer.FacetValue{}
+	}
+
+	writeJSON(w, http.StatusOK, rollup)
+}
+
+// apiFilters - available filter values
+func (s *Server) apiFilters(w http.ResponseWriter, r *http.Request) {
+	options, err := s.indexer.GetFilterOptions(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, options)
+}
+
+// apiDictionaries - synced Tracker dictionaries by kind
+func (s *Server) apiDictionaries(w http.ResponseWriter, r *http.Request) {
+	dicts, err := s.indexer.Dictionaries(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dicts.All())
+}
+
+// apiWorklogReport - logged hours of matching issues grouped by person, queue, issue or week,
+// as JSON or CSV with format=csv
+func (s *Server) apiWorklogReport(w http.ResponseWriter, r *http.Request) {
+	opts := indexer.ParseWorklogOptions(r.URL.Query())
+	if !indexer.IsWorklogDimension(opts.By) {
+		writeError(w, http.StatusBadRequest, "bad_request", "unsupported report dimension: "+opts.By)
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	filters := indexer.ParseSearchFilters(r.URL.Query())
+
+	report, err := s.indexer.WorklogReport(r.Context(), query, filters, opts)
+	if err != nil {
+		log.Printf("Worklog report error: %v", err)
+		writeError(w, http.StatusInternalServerError, "report_failed", err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		writeWorklogCSV(w, report)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// writeWorklogCSV - writes a worklog report as a CSV attachment
+func writeWorklogCSV(w http.ResponseWriter, report *indexer.WorklogReport) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="worklog-%s.csv"`, report.By))
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{report.By, "label", "hours", "entries"})
+	for _, row := range report.Rows {
+		cw.Write([]string{
+			row.Value,
+			row.Label,
+			strconv.FormatFloat(row.Hours, 'f', 2, 64),
+			strconv.Itoa(row.Entries),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("CSV write error: %v", err)
+	}
+}
+
+// apiFlowMetrics - lead and cycle time percentiles of resolved matching issues
+// grouped by queue, type or period of resolution
+func (s *Server) apiFlowMetrics(w http.ResponseWriter, r *http.Request) {
+	opts := indexer.ParseFlowOptions(r.URL.Query())
+	if !indexer.IsFlowDimension(opts.By) {
+		writeError(w, http.StatusBadRequest, "bad_request", "unsupported report dimension: "+opts.By)
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	filters := indexer.ParseSearchFilters(r.URL.Query())
+
+	report, err := s.indexer.FlowReport(r.Context(), query, filters, opts)
+	if err != nil {
+		log.Printf("Flow report error: %v", err)
+		writeError(w, http.StatusInternalServerError, "report_failed", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// apiInbox - issues where the user was summoned or mentioned and has not commented since
+func (s *Server) apiInbox(w http.ResponseWriter, r *http.Request) {
+	opts := indexer.ParseInboxOptions(r.URL.Query())
+	if opts.User == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "user is required")
+		return
+	}
+
+	items, err := s.indexer.Inbox(r.Context(), opts)
+	if err != nil {
+		log.Printf("Inbox error: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, items)
+}
+
+// apiSyncStatus - current synchronization status
+func (s *Server) apiSyncStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.syncManager.GetStatus())
+}
+
+// apiSyncTrigger - starts synchronization
+func (s *Server) apiSyncTrigger(w http.ResponseWriter, r *http.Request) {
+	if err := s.syncManager.TriggerSync(); err != nil {
+		writeError(w, http.StatusConflict, "sync_in_progress", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, s.syncManager.GetStatus())
+}
+
+// apiSyncCancel - cancels running synchronization
+func (s *Server) apiSyncCancel(w http.ResponseWriter, r *http.Request) {
+	if err := s.syncManager.CancelSync(); err != nil {
+		writeError(w, http.StatusConflict, "sync_not_running", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.syncManager.GetStatus())
+}
+
+// apiSyncLogs - recent synchronization log entries, newest first
+func (s *Server) apiSyncLogs(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", 100)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.syncManager.GetLogs(limit))
+}
+
+// apiSyncConfirmDeletions - removes stale issues kept by reconciliation until confirmed
+func (s *Server) apiSyncConfirmDeletions(w http.ResponseWriter, r *http.Request) {
+	err := s.syncManager.ConfirmDeletions(r.Context())
+	switch {
+	case errors.Is(err, sync.ErrNoPendingDeletions):
+		writeError(w, http.StatusConflict, "nothing_to_confirm", err.Error())
+		return
+	case errors.Is(err, sync.ErrSyncInProgress):
+		writeError(w, http.StatusConflict, "sync_in_progress", err.Error())
+		return
+	case err != nil:
+		log.Printf("Confirm deletions error: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.syncManager.GetStatus())
+}
+
+// apiSpec - OpenAPI document of the JSON API
+func (s *Server) apiSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(openAPISpec)
+}
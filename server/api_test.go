@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ytbs/indexer"
+	"ytbs/indexer/indexertest"
+	"ytbs/sync"
+	"ytbs/tracker"
+)
+
+// groupedRows - rows of a search grouped by queue, top results of the largest group first
+var groupedRows = []map[string]any{
+	{"id": 1, "issue_key": "OPS-1", "queue": "OPS", "group_total": 4},
+	{"id": 2, "issue_key": "OPS-2", "queue": "OPS", "group_total": 4},
+	{"id": 3, "issue_key": "OPS-3", "queue": "OPS", "group_total": 4},
+	{"id": 4, "issue_key": "TEST-1", "queue": "TEST", "group_total": 2},
+	{"id": 5, "issue_key": "TEST-2", "queue": "TEST", "group_total": 2},
+}
+
+var limitRe = regexp.MustCompile(`LIMIT (\d+), (\d+)`)
+
+// groupedTable - answers grouped searches with a range of groupedRows and counts with the number of issues
+func groupedTable(sql string) ([]map[string]any, error) {
+	switch {
+	case strings.Contains(sql, "COUNT(*) as cnt"):
+		return []map[string]any{{"cnt": 6}}, nil
+	case strings.Contains(sql, "GROUP 3 BY queue"):
+		m := limitRe.FindStringSubmatch(sql)
+		if m == nil {
+			return groupedRows, nil
+		}
+		offset, _ := strconv.Atoi(m[1])
+		limit, _ := strconv.Atoi(m[2])
+		return groupedRows[min(offset, len(groupedRows)):min(offset+limit, len(groupedRows))], nil
+	}
+	return nil, nil
+}
+
+// newTestAPI - starts the API of a server over a fake index
+func newTestAPI(t *testing.T, handler indexertest.Handler) (*httptest.Server, *indexertest.Server) {
+	t.Helper()
+	db := indexertest.NewServer(t, handler)
+	idx := indexer.NewIndexer(db.URL)
+	manager := sync.NewManager(tracker.NewClient("", ""), idx, tracker.SyncOptions{Workers: 1}, time.Hour)
+
+	srv, err := NewServer("", idx, manager)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	api := httptest.NewServer(srv.APIHandler())
+	t.Cleanup(api.Close)
+	return api, db
+}
+
+func TestAPISearchGroupedPages(t *testing.T) {
+	api, _ := newTestAPI(t, groupedTable)
+
+	tests := []struct {
+		offset string
+		keys   []string
+		groups []string
+	}{
+		{"0", []string{"OPS-1", "OPS-2", "OPS-3"}, []string{"OPS"}},
+		{"3", []string{"TEST-1", "TEST-2"}, []string{"TEST"}},
+		{"6", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run("offset "+tt.offset, func(t *testing.T) {
+			resp, err := http.Get(api.URL + "/api/v1/search?group_by=queue&limit=3&offset=" + tt.offset)
+			if err != nil {
+				t.Fatalf("GET: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status %d, want 200", resp.StatusCode)
+			}
+
+			var page indexer.SearchPage
+			if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if page.Total != 6 || strconv.Itoa(page.Offset) != tt.offset || page.Limit != 3 {
+				t.Errorf("total, offset, limit = %d, %d, %d, want 6, %s, 3", page.Total, page.Offset, page.Limit, tt.offset)
+			}
+
+			var keys, groups []string
+			for _, r := range page.Results {
+				keys = append(keys, r.Key)
+			}
+			for _, g := range page.Groups {
+				groups = append(groups, g.Value)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.keys, ",") {
+				t.Errorf("results %v, want %v", keys, tt.keys)
+			}
+			if strings.Join(groups, ",") != strings.Join(tt.groups, ",") {
+				t.Errorf("groups %v, want %v", groups, tt.groups)
+			}
+		})
+	}
+}
+
+func TestAPIConfirmDeletionsWithoutPending(t *testing.T) {
+	api, _ := newTestAPI(t, groupedTable)
+
+	resp, err := http.Post(api.URL+"/api/v1/sync/deletions", "", nil)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.StatusCode != http.StatusConflict || body.Error.Code != "nothing_to_confirm" {
+		t.Errorf("status %d, code %q, want 409, nothing_to_confirm", resp.StatusCode, body.Error.Code)
+	}
+}
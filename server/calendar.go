@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bufio"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ytbs/indexer"
+)
+
+// icsLineLimit - maximum length of an iCalendar content line in octets
+const icsLineLimit = 75
+
+// icsEscaper - escapes iCalendar text values
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// apiCalendar - iCalendar feed of deadlines of issues matching the query and filters,
+// for subscription in calendar apps
+func (s *Server) apiCalendar(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	filters := indexer.ParseSearchFilters(r.URL.Query())
+
+	events, err := s.indexer.Calendar(r.Context(), query, filters)
+	if err != nil {
+		log.Printf("Calendar error: %v", err)
+		writeError(w, http.StatusInternalServerError, "calendar_failed", err.Error())
+		return
+	}
+
+	name := "Дедлайны"
+	if query != "" {
+		name += ": " + query
+	}
+	writeCalendar(w, name, events)
+}
+
+// writeCalendar - writes deadlines as all-day iCalendar events
+func writeCalendar(w http.ResponseWriter, name string, events []indexer.CalendarEvent) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="deadlines.ics"`)
+
+	bw := bufio.NewWriter(w)
+	line := func(s string) {
+		bw.WriteString(foldICSLine(s))
+		bw.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//ytbs//Yandex Tracker Better Search//RU")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + icsEscaper.Replace(name))
+
+	for _, e := range events {
+		day := e.Deadline.Local()
+		description := "Статус: " + e.StatusName
+		if e.AssigneeName != "" {
+			description += "\nИсполнитель: " + e.AssigneeName
+		}
+
+		line("BEGIN:VEVENT")
+		line("UID:" + e.Key + "-deadline@ytbs")
+		stamp := e.UpdatedAt
+		if stamp.IsZero() {
+			stamp = time.Now()
+		}
+		line("DTSTAMP:" + stamp.UTC().Format("20060102T150405Z"))
+		line("DTSTART;VALUE=DATE:" + day.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + day.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + icsEscaper.Replace(e.Key+": "+e.Summary))
+		line("DESCRIPTION:" + icsEscaper.Replace(description))
+		if e.URL != "" {
+			line("URL:" + e.URL)
+		}
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	if err := bw.Flush(); err != nil {
+		log.Printf("Calendar write error: %v", err)
+	}
+}
+
+// foldICSLine - splits a content line longer than the limit into continuation lines,
+// keeping multi-byte characters whole
+func foldICSLine(s string) string {
+	if len(s) <= icsLineLimit {
+		return s
+	}
+
+	var b strings.Builder
+	limit := icsLineLimit
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// continuation lines start with a space
+		limit = icsLineLimit - 1
+	}
+	b.WriteString(s)
+	return b.String()
+}
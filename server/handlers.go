@@ -1,10 +1,15 @@
 package server
 
 import (
+	"errors"
+	"html/template"
 	"log"
 	"net/http"
+	"net/url"
+	"time"
 
 	"ytbs/indexer"
+	"ytbs/tracker"
 )
 
 // handleIndex - main page
@@ -22,16 +27,91 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := struct {
-		Status  any
-		Filters *indexer.FilterOptions
+		Status    any
+		Filters   *indexer.FilterOptions
+		Relations []relationOption
 	}{
-		Status:  s.syncManager.GetStatus(),
-		Filters: filterOptions,
+		Status:    s.syncManager.GetStatus(),
+		Filters:   filterOptions,
+		Relations: relationOptions,
 	}
 
 	s.templates.ExecuteTemplate(w, "index.html", data)
 }
 
+// timelineEntry - changelog entry of the issue page: all field changes made at once
+type timelineEntry struct {
+	Time       time.Time
+	AuthorName string
+	Changes    []tracker.IndexedChange
+}
+
+// buildTimeline - groups field changes by changelog entry preserving their order
+func buildTimeline(changes []tracker.IndexedChange) []timelineEntry {
+	var timeline []timelineEntry
+	for _, c := range changes {
+		if n := len(timeline); n > 0 && timeline[n-1].Changes[0].ChangeID == c.ChangeID {
+			timeline[n-1].Changes = append(timeline[n-1].Changes, c)
+			continue
+		}
+		timeline = append(timeline, timelineEntry{
+			Time:       c.ChangedAt,
+			AuthorName: c.AuthorName,
+			Changes:    []tracker.IndexedChange{c},
+		})
+	}
+	return timeline
+}
+
+// relationOption - link relation choice in the search form
+type relationOption struct {
+	Value string
+	Name  string
+}
+
+// relationOptions - link relations in display order
+var relationOptions = []relationOption{
+	{tracker.RelationRelates, "Связана с"},
+	{tracker.RelationDependsOn, "Зависит от"},
+	{tracker.RelationBlocks, "Блокирует"},
+	{tracker.RelationDuplicates, "Дублирует"},
+	{tracker.RelationDuplicatedBy, "Дублируется"},
+	{tracker.RelationSubtaskOf, "Подзадача"},
+	{tracker.RelationParentOf, "Родительская задача"},
+	{tracker.RelationHasEpic, "Входит в эпик"},
+	{tracker.RelationEpicOf, "Эпик для"},
+}
+
+// relationName - display name of a link relation
+func relationName(relation string) string {
+	for _, r := range relationOptions {
+		if r.Value == relation {
+			return r.Name
+		}
+	}
+	return relation
+}
+
+// categoryNames - display names of status categories
+var categoryNames = map[string]string{
+	tracker.CategoryOpen:       "Открыта",
+	tracker.CategoryInProgress: "В работе",
+	tracker.CategoryDone:       "Завершена",
+}
+
+// categoryName - display name of a status category
+func categoryName(category string) string {
+	if name, ok := categoryNames[category]; ok {
+		return name
+	}
+	return category
+}
+
+// overdue - checks if an unfinished issue is past its deadline
+func overdue(deadline time.Time, statusCategory string) bool {
+	return indexer.Overdue(deadline, statusCategory, time.Now())
+}
+
 // handleLogs - logs page
 func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 	data := struct {
@@ -45,36 +125,189 @@ func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 	s.templates.ExecuteTemplate(w, "logs.html", data)
 }
 
+// worklogDimensionNames - report dimension labels
+var worklogDimensionNames = []struct {
+	Value  string
+	Name   string
+	Column string
+}{
+	{"person", "По сотрудникам", "Сотрудник"},
+	{"queue", "По очередям", "Очередь"},
+	{"issue", "По задачам", "Задача"},
+	{"week", "По неделям", "Неделя"},
+}
+
+// handleReport - time-tracking report page for issues matching the search filters
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	opts := indexer.ParseWorklogOptions(r.URL.Query())
+	if !indexer.IsWorklogDimension(opts.By) {
+		opts.By = "person"
+	}
+
+	query := r.URL.Query().Get("q")
+	filters := indexer.ParseSearchFilters(r.URL.Query())
+
+	// search parameters are kept as hidden form fields
+	params := filters.Query()
+	if query != "" {
+		params.Set("q", query)
+	}
+
+	csvParams := opts.Query()
+	for name, values := range params {
+		csvParams[name] = values
+	}
+	csvParams.Set("format", "csv")
+
+	data := struct {
+		Report     *indexer.WorklogReport
+		Options    indexer.WorklogOptions
+		Dimensions any
+		Params     url.Values
+		SearchURL  string
+		CSVURL     string
+		Error      string
+	}{
+		Options:    opts,
+		Dimensions: worklogDimensionNames,
+		Params:     params,
+		SearchURL:  "/?" + params.Encode(),
+		CSVURL:     "/api/v1/worklog/report?" + csvParams.Encode(),
+	}
+
+	report, err := s.indexer.WorklogReport(r.Context(), query, filters, opts)
+	if err != nil {
+		log.Printf("Worklog report error: %v", err)
+		data.Error = err.Error()
+	}
+	data.Report = report
+
+	if err := s.templates.ExecuteTemplate(w, "report.html", data); err != nil {
+		log.Printf("Template error: %v", err)
+	}
+}
+
+// flowDimensionNames - flow report dimension labels
+var flowDimensionNames = []struct {
+	Value  string
+	Name   string
+	Column string
+}{
+	{"queue", "По очередям", "Очередь"},
+	{"type", "По типам", "Тип"},
+	{"period", "По периодам", "Период"},
+}
+
+// handleFlow - lead and cycle time page for resolved issues matching the search filters
+func (s *Server) handleFlow(w http.ResponseWriter, r *http.Request) {
+	opts := indexer.ParseFlowOptions(r.URL.Query())
+	if !indexer.IsFlowDimension(opts.By) {
+		opts.By = "queue"
+	}
+
+	query := r.URL.Query().Get("q")
+	filters := indexer.ParseSearchFilters(r.URL.Query())
+
+	// search parameters are kept as hidden form fields
+	params := filters.Query()
+	if query != "" {
+		params.Set("q", query)
+	}
+
+	apiParams := opts.Query()
+	for name, values := range params {
+		apiParams[name] = values
+	}
+
+	data := struct {
+		Report     *indexer.FlowReport
+		Options    indexer.FlowOptions
+		Dimensions any
+		Params     url.Values
+		SearchURL  string
+		JSONURL    string
+		Error      string
+	}{
+		Options:    opts,
+		Dimensions: flowDimensionNames,
+		Params:     params,
+		SearchURL:  "/?" + params.Encode(),
+		JSONURL:    "/api/v1/metrics/flow?" + apiParams.Encode(),
+	}
+
+	report, err := s.indexer.FlowReport(r.Context(), query, filters, opts)
+	if err != nil {
+		log.Printf("Flow report error: %v", err)
+		data.Error = err.Error()
+	}
+	data.Report = report
+
+	if err := s.templates.ExecuteTemplate(w, "flow.html", data); err != nil {
+		log.Printf("Template error: %v", err)
+	}
+}
+
+// handleInbox - issues awaiting reply from the user
+func (s *Server) handleInbox(w http.ResponseWriter, r *http.Request) {
+	opts := indexer.ParseInboxOptions(r.URL.Query())
+
+	data := struct {
+		Items   []indexer.InboxItem
+		Options indexer.InboxOptions
+		Error   string
+	}{
+		Options: opts,
+	}
+
+	items, err := s.indexer.Inbox(r.Context(), opts)
+	if err != nil {
+		log.Printf("Inbox error: %v", err)
+		data.Error = err.Error()
+	}
+	data.Items = items
+
+	if err := s.templates.ExecuteTemplate(w, "inbox.html", data); err != nil {
+		log.Printf("Template error: %v", err)
+	}
+}
+
 // handleSearch - search API (htmx)
 func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 
 	// Get filter parameters
-	filters := indexer.SearchFilters{
-		Queue:    r.URL.Query().Get("queue"),
-		Status:   r.URL.Query().Get("status"),
-		Priority: r.URL.Query().Get("priority"),
-		Author:   r.URL.Query().Get("author"),
-		Assignee: r.URL.Query().Get("assignee"),
-	}
+	filters := indexer.ParseSearchFilters(r.URL.Query())
 
 	// Unified data structure for template
 	data := struct {
 		Query   string
 		Results any
+		Groups  []indexer.ResultGroup
 		Count   int
 		Error   string
 		Filters indexer.SearchFilters
+		// ReportURL - time-tracking report of the same search
+		ReportURL string
+		// CalendarURL - iCalendar feed of deadlines of the same search
+		CalendarURL string
+		// FlowURL - lead and cycle time of the same search
+		FlowURL string
 	}{
 		Query:   query,
 		Filters: filters,
 	}
 
-	// Check if we have any search criteria
-	hasFilters := filters.Queue != "" || filters.Status != "" || filters.Priority != "" ||
-		filters.Author != "" || filters.Assignee != ""
+	reportParams := filters.Query()
+	if query != "" {
+		reportParams.Set("q", query)
+	}
+	data.ReportURL = "/report?" + reportParams.Encode()
+	reportParams.Del("group_by")
+	data.FlowURL = "/flow?" + reportParams.Encode()
+	data.CalendarURL = "/api/v1/calendar.ics?" + reportParams.Encode()
 
-	if query == "" && !hasFilters {
+	// Check if we have any search criteria
+	if query == "" && filters.IsEmpty() {
 		s.templates.ExecuteTemplate(w, "results.html", data)
 		return
 	}
@@ -89,6 +322,13 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 
 	data.Results = results
 	data.Count = len(results)
+	if filters.GroupBy != "" {
+		data.Groups = indexer.GroupResults(results)
+		data.Count = 0
+		for _, g := range data.Groups {
+			data.Count += g.Total
+		}
+	}
 	log.Printf("Search query: %q, filters: %+v, results: %d", query, filters, len(results))
 
 	if err := s.templates.ExecuteTemplate(w, "results.html", data); err != nil {
@@ -118,3 +358,95 @@ func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
 
 	s.templates.ExecuteTemplate(w, "status.html", s.syncManager.GetStatus())
 }
+
+// handleSyncDeletions - confirms removal of stale issues (htmx)
+func (s *Server) handleSyncDeletions(w http.ResponseWriter, r *http.Request) {
+	if err := s.syncManager.ConfirmDeletions(r.Context()); err != nil {
+		w.Header().Set("HX-Trigger", "sync-error")
+	} else {
+		w.Header().Set("HX-Trigger", "sync-deletions-confirmed")
+	}
+
+	s.templates.ExecuteTemplate(w, "status.html", s.syncManager.GetStatus())
+}
+
+// handleIssue - issue page rendered from the local index
+func (s *Server) handleIssue(w http.ResponseWriter, r *http.Request) {
+	issue, err := s.indexer.GetIssue(r.Context(), r.PathValue("key"))
+	if errors.Is(err, indexer.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading issue %s: %v", r.PathValue("key"), err)
+		http.Error(w, "Failed to load issue", http.StatusInternalServerError)
+		return
+	}
+	if issue.Key != r.PathValue("key") {
+		// the issue was requested by a key it had before moving to another queue
+		target := "/issue/" + url.PathEscape(issue.Key)
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	renderer := newMarkupRenderer(searchTerms(query))
+
+	type commentView struct {
+		tracker.IndexedComment
+		HTML template.HTML
+	}
+
+	rollup, err := s.indexer.GetRollup(r.Context(), issue.Key)
+	if err != nil {
+		log.Printf("Error loading rollup of %s: %v", issue.Key, err)
+		rollup = &indexer.Rollup{}
+	}
+
+	data := struct {
+		Issue       *tracker.IndexedIssue
+		Query       string
+		Summary     template.HTML
+		Description template.HTML
+		Comments    []commentView
+		Rollup      *indexer.Rollup
+		Timeline    []timelineEntry
+		// WorklogSeconds - total time logged on the issue
+		WorklogSeconds int64
+		// ChecklistDone - number of checked checklist items
+		ChecklistDone int
+		Now           time.Time
+		// CustomValues - values of declared custom fields
+		CustomValues []indexer.CustomValue
+	}{
+		Issue:        issue,
+		Query:        query,
+		Summary:      template.HTML(renderer.text(issue.Summary)),
+		Description:  renderer.render(issue.DescriptionRaw),
+		Rollup:       rollup,
+		Timeline:     buildTimeline(issue.Changes),
+		Now:          time.Now(),
+		CustomValues: indexer.CustomValues(issue.CustomFields),
+	}
+
+	for _, wl := range issue.Worklog {
+		data.WorklogSeconds += wl.Seconds
+	}
+
+	for _, item := range issue.Checklist {
+		if item.Checked {
+			data.ChecklistDone++
+		}
+	}
+
+	for _, c := range issue.Comments {
+		data.Comments = append(data.Comments, commentView{IndexedComment: c, HTML: renderer.render(c.Text)})
+	}
+
+	if err := s.templates.ExecuteTemplate(w, "issue.html", data); err != nil {
+		log.Printf("Template error: %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+var (
+	// inline markup: `code`, [text](url), **bold**, bare URLs and issue keys
+	inlineRe = regexp.MustCompile("`([^`]+)`" +
+		`|\[([^\]]*)\]\(([^)\s]+)\)` +
+		`|\*\*([^*]+)\*\*` +
+		`|(https?://[^\s<>()]+)` +
+		`|\b([A-Z][A-Z0-9]+-\d+)\b`)
+	headingRe   = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	listItemRe  = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(.*)$`)
+	cutStartRe  = regexp.MustCompile(`^\{%\s*cut\s+"([^"]*)"\s*%\}$`)
+	blockTagRe  = regexp.MustCompile(`^\{%\s*(end\w+|note\b[^%]*|list\b[^%]*)\s*%\}$`)
+	termSplitRe = regexp.MustCompile(`[\p{L}\p{N}]{2,}`)
+)
+
+// searchTerms - extracts words of a search query for highlighting.
+// Long words are cut to a rough stem so that other word forms are highlighted too,
+// matching the stemming used by the index
+func searchTerms(query string) []string {
+	words := termSplitRe.FindAllString(query, -1)
+	for i, w := range words {
+		if r := []rune(w); len(r) > 5 {
+			words[i] = string(r[:len(r)-2])
+		}
+	}
+	return words
+}
+
+// markupRenderer - renders Tracker (YFM) markup to safe HTML with highlighted search terms
+type markupRenderer struct {
+	highlight *regexp.Regexp
+}
+
+// newMarkupRenderer - creates a renderer highlighting the given terms
+func newMarkupRenderer(terms []string) *markupRenderer {
+	r := &markupRenderer{}
+	if len(terms) > 0 {
+		quoted := make([]string, len(terms))
+		for i, t := range terms {
+			quoted[i] = regexp.QuoteMeta(t)
+		}
+		r.highlight = regexp.MustCompile(`(?i)(` + strings.Join(quoted, "|") + `)`)
+	}
+	return r
+}
+
+// text - escapes plain text and highlights search terms in it
+func (r *markupRenderer) text(s string) string {
+	if r.highlight == nil {
+		return html.EscapeString(s)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range r.highlight.FindAllStringIndex(s, -1) {
+		b.WriteString(html.EscapeString(s[last:m[0]]))
+		b.WriteString("<mark>" + html.EscapeString(s[m[0]:m[1]]) + "</mark>")
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(s[last:]))
+	return b.String()
+}
+
+// inline - renders inline markup of a single line
+func (r *markupRenderer) inline(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range inlineRe.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(r.text(s[last:m[0]]))
+		last = m[1]
+
+		group := func(n int) string {
+			if m[2*n] < 0 {
+				return ""
+			}
+			return s[m[2*n]:m[2*n+1]]
+		}
+
+		switch {
+		case m[2] >= 0:
+			b.WriteString("<code>" + html.EscapeString(group(1)) + "</code>")
+		case m[6] >= 0:
+			label := group(2)
+			if label == "" {
+				label = group(3)
+			}
+			b.WriteString(`<a href="` + html.EscapeString(safeURL(group(3))) + `" target="_blank" rel="noopener">` + r.text(label) + "</a>")
+		case m[8] >= 0:
+			b.WriteString("<strong>" + r.text(group(4)) + "</strong>")
+		case m[10] >= 0:
+			b.WriteString(`<a href="` + html.EscapeString(group(5)) + `" target="_blank" rel="noopener">` + r.text(group(5)) + "</a>")
+		case m[12] >= 0:
+			b.WriteString(`<a href="/issue/` + group(6) + `" class="issue-ref">` + r.text(group(6)) + "</a>")
+		}
+	}
+	b.WriteString(r.text(s[last:]))
+	return b.String()
+}
+
+// render - renders a markup document
+func (r *markupRenderer) render(src string) template.HTML {
+	var b strings.Builder
+	var paragraph []string
+	var list []string
+	inCode := false
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + strings.Join(paragraph, "<br>") + "</p>\n")
+			paragraph = nil
+		}
+	}
+	flushList := func() {
+		if len(list) > 0 {
+			b.WriteString("<ul>")
+			for _, item := range list {
+				b.WriteString("<li>" + item + "</li>")
+			}
+			b.WriteString("</ul>\n")
+			list = nil
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			flushParagraph()
+			flushList()
+			if inCode {
+				b.WriteString("</code></pre>\n")
+			} else {
+				b.WriteString("<pre><code>")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			b.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+
+		if trimmed == "" {
+			flushParagraph()
+			flushList()
+			continue
+		}
+
+		if m := cutStartRe.FindStringSubmatch(trimmed); m != nil {
+			flushParagraph()
+			flushList()
+			b.WriteString("<details><summary>" + r.inline(m[1]) + "</summary>\n")
+			continue
+		}
+		if m := blockTagRe.FindStringSubmatch(trimmed); m != nil {
+			flushParagraph()
+			flushList()
+			switch {
+			case m[1] == "endcut":
+				b.WriteString("</details>\n")
+			case strings.HasPrefix(m[1], "note"):
+				b.WriteString(`<div class="note">` + "\n")
+			case m[1] == "endnote":
+				b.WriteString("</div>\n")
+			}
+			continue
+		}
+
+		if m := headingRe.FindStringSubmatch(trimmed); m != nil {
+			flushParagraph()
+			flushList()
+			level := string(rune('0' + len(m[1])))
+			b.WriteString("<h" + level + ">" + r.inline(m[2]) + "</h" + level + ">\n")
+			continue
+		}
+
+		if m := listItemRe.FindStringSubmatch(line); m != nil {
+			flushParagraph()
+			list = append(list, r.inline(m[1]))
+			continue
+		}
+
+		flushList()
+		if strings.HasPrefix(trimmed, ">") {
+			flushParagraph()
+			b.WriteString("<blockquote>" + r.inline(strings.TrimSpace(strings.TrimLeft(trimmed, ">"))) + "</blockquote>\n")
+			continue
+		}
+
+		paragraph = append(paragraph, r.inline(trimmed))
+	}
+
+	flushParagraph()
+	flushList()
+	if inCode {
+		b.WriteString("</code></pre>\n")
+	}
+
+	return template.HTML(b.String())
+}
+
+// safeURL - allows only http(s), mailto and relative links
+func safeURL(u string) string {
+	lower := strings.ToLower(u)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") ||
+		strings.HasPrefix(lower, "mailto:") || strings.HasPrefix(lower, "/") {
+		return u
+	}
+	return "#"
+}
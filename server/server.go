@@ -3,9 +3,12 @@ package server
 import (
 	"context"
 	"embed"
+	"fmt"
 	"html/template"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"ytbs/indexer"
@@ -49,9 +52,25 @@ func NewServer(addr string, indexer *indexer.Indexer, syncManager *sync.Manager)
 				return t.Format("02.01.2006 15:04")
 			}
 		},
+		"relationName": relationName,
+		"categoryName": categoryName,
+		"overdue":      overdue,
+		"formatDate": func(t time.Time) string {
+			return t.Local().Format("02.01.2006")
+		},
+		"fileSize":  fileSize,
+		"workHours": workHours,
 		"safeHTML": func(s string) template.HTML {
 			return template.HTML(s)
 		},
+		"dict": func(pairs ...any) map[string]any {
+			m := make(map[string]any, len(pairs)/2)
+			for i := 0; i+1 < len(pairs); i += 2 {
+				key, _ := pairs[i].(string)
+				m[key] = pairs[i+1]
+			}
+			return m
+		},
 	}).ParseFS(templatesFS, "templates/*.html")
 	if err != nil {
 		return nil, err
@@ -76,6 +95,23 @@ func formatDuration(n float64, one, few, many string) string {
 	return string(rune('0'+i%10)) + " " + many
 }
 
+// fileSize - formats a size in bytes for display
+func fileSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f МБ", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f КБ", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d Б", size)
+	}
+}
+
+// workHours - formats logged seconds as hours for display
+func workHours(seconds int64) string {
+	return strconv.FormatFloat(math.Round(float64(seconds)/36)/100, 'f', -1, 64) + " ч"
+}
+
 // Start - starts the HTTP server
 func (s *Server) Start(ctx context.Context) error {
 	mux := http.NewServeMux()
@@ -83,11 +119,20 @@ func (s *Server) Start(ctx context.Context) error {
 	// Pages
 	mux.HandleFunc("/", s.handleIndex)
 	mux.HandleFunc("/logs", s.handleLogs)
+	mux.HandleFunc("GET /report", s.handleReport)
+	mux.HandleFunc("GET /flow", s.handleFlow)
+	mux.HandleFunc("GET /inbox", s.handleInbox)
+	mux.HandleFunc("GET /issue/{key}", s.handleIssue)
 
 	// API
 	mux.HandleFunc("/api/search", s.handleSearch)
 	mux.HandleFunc("/api/status", s.handleStatus)
 	mux.HandleFunc("/api/sync", s.handleSync)
+	mux.HandleFunc("POST /api/sync/deletions", s.handleSyncDeletions)
+	mux.HandleFunc("GET /api/issues/{key}/graph", s.apiIssueGraph)
+
+	// JSON API
+	s.registerAPI(mux)
 
 	server := &http.Server{
 		Addr:    s.addr,
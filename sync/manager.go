@@ -2,8 +2,10 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,6 +13,15 @@ import (
 	"ytbs/tracker"
 )
 
+// ErrSyncInProgress - the operation needs no sync to be running
+var ErrSyncInProgress = errors.New("sync already in progress")
+
+// ErrNoPendingDeletions - no stale issues await confirmation of their removal
+var ErrNoPendingDeletions = errors.New("no deletions pending")
+
+// defaultMaxDeletePercent - share of indexed issues removed by reconciliation without confirmation
+const defaultMaxDeletePercent = indexer.DefaultMaxDeletePercent
+
 // Status - synchronization status
 type Status struct {
 	InProgress    bool      `json:"in_progress"`
@@ -19,20 +30,68 @@ type Status struct {
 	IssuesCount   int       `json:"issues_count"`
 	CommentsCount int       `json:"comments_count"`
 	Duration      string    `json:"duration,omitempty"`
+	// Processed, Total - issues processed by the running sync and all listed issues
+	Processed int `json:"processed,omitempty"`
+	Total     int `json:"total,omitempty"`
+	// ResumedFrom - issues processed by an interrupted sync the running one continues from
+	ResumedFrom int `json:"resumed_from,omitempty"`
+	// Phase - running phase: recent issues first, then backfill of older ones
+	Phase string `json:"phase,omitempty"`
+	// Recent, Backfill - progress of the phases of the running or last sync
+	Recent   tracker.PhaseProgress `json:"recent"`
+	Backfill tracker.PhaseProgress `json:"backfill"`
+	// Partitions - listing progress and errors per queue or creation year
+	Partitions []tracker.PartitionProgress `json:"partitions,omitempty"`
+	// PendingDeletions - stale issues kept in the index until their removal is confirmed
+	PendingDeletions int `json:"pending_deletions,omitempty"`
+}
+
+// Listed - number of issues listed so far, before duplicates across partitions are merged
+func (s Status) Listed() int {
+	listed := 0
+	for _, p := range s.Partitions {
+		listed += p.Fetched
+	}
+	return listed
+}
+
+// PartitionsDone - number of partitions listed completely or failed
+func (s Status) PartitionsDone() int {
+	done := 0
+	for _, p := range s.Partitions {
+		if p.Done {
+			done++
+		}
+	}
+	return done
+}
+
+// FailedPartitions - partitions that could not be listed
+func (s Status) FailedPartitions() []tracker.PartitionProgress {
+	var failed []tracker.PartitionProgress
+	for _, p := range s.Partitions {
+		if p.Error != "" {
+			failed = append(failed, p)
+		}
+	}
+	return failed
 }
 
 // Manager - synchronization manager
 type Manager struct {
 	tracker  *tracker.Client
 	indexer  *indexer.Indexer
-	queues   []string
-	workers  int
+	options  tracker.SyncOptions
 	interval time.Duration
+	// maxDeletePercent - share of indexed issues removed by reconciliation without confirmation
+	maxDeletePercent int
 
 	mu             sync.RWMutex
 	status         Status
 	logs           []LogEntry
 	requestChannel chan bool
+	// pendingDeletions - keys of stale issues awaiting confirmation of their removal
+	pendingDeletions []string
 }
 
 // LogEntry - html log entry
@@ -42,17 +101,21 @@ type LogEntry struct {
 	Message string    `json:"message"`
 }
 
-// NewManager - creates sync manager instance
-func NewManager(tracker *tracker.Client, indexer *indexer.Indexer, queues []string, workers int, interval time.Duration) *Manager {
-	return &Manager{
-		tracker:        tracker,
-		indexer:        indexer,
-		queues:         queues,
-		workers:        workers,
-		interval:       interval,
-		logs:           make([]LogEntry, 0, 100),
-		requestChannel: make(chan bool, 1),
+// NewManager - creates sync manager instance.
+// The changelog cursor, the attachment cache and the checkpoint of options are provided by the indexer,
+// processed issues are indexed as the sync goes
+func NewManager(tracker *tracker.Client, indexer *indexer.Indexer, options tracker.SyncOptions, interval time.Duration) *Manager {
+	m := &Manager{
+		tracker:          tracker,
+		indexer:          indexer,
+		options:          indexer.SyncOptions(options),
+		interval:         interval,
+		maxDeletePercent: defaultMaxDeletePercent,
+		logs:             make([]LogEntry, 0, 100),
+		requestChannel:   make(chan bool, 1),
 	}
+	m.options.Progress = m.setProgress
+	return m
 }
 
 // Start - starts periodic synchronization
@@ -97,6 +160,10 @@ func (m *Manager) RunSync(ctx context.Context) {
 	m.mu.Lock()
 	m.status.InProgress = true
 	m.status.LastSyncError = ""
+	m.status.Processed, m.status.Total, m.status.ResumedFrom = 0, 0, 0
+	m.status.Phase = ""
+	m.status.Recent, m.status.Backfill = tracker.PhaseProgress{}, tracker.PhaseProgress{}
+	m.status.Partitions = nil
 	m.mu.Unlock()
 
 	startTime := time.Now()
@@ -108,7 +175,20 @@ func (m *Manager) RunSync(ctx context.Context) {
 		m.mu.Unlock()
 	}()
 
-	issues, result, err := m.tracker.InitialSync(ctx, m.queues, m.workers)
+	// stale names are better than none, so sync goes on without dictionaries
+	if entries, err := m.tracker.FetchDictionaries(ctx); err != nil {
+		m.addLog("warning", fmt.Sprintf("Failed to fetch dictionaries: %v", err))
+	} else if err := m.indexer.IndexDictionaries(ctx, entries); err != nil {
+		m.addLog("warning", fmt.Sprintf("Failed to index dictionaries: %v", err))
+	}
+
+	// issues are indexed in batches by the sync, so an interrupted one resumes from the checkpoint
+	_, result, err := m.tracker.InitialSync(ctx, m.options)
+	for _, p := range result.Partitions {
+		if p.Error != "" {
+			m.addLog("error", fmt.Sprintf("Failed to list issues of partition %s: %s", p.Name, p.Error))
+		}
+	}
 	if err != nil {
 		m.mu.Lock()
 		m.status.LastSyncError = err.Error()
@@ -117,12 +197,10 @@ func (m *Manager) RunSync(ctx context.Context) {
 		return
 	}
 
-	if err := m.indexer.IndexIssues(ctx, issues); err != nil {
-		m.mu.Lock()
-		m.status.LastSyncError = err.Error()
-		m.mu.Unlock()
-		m.addLog("error", fmt.Sprintf("Indexing failed: %v", err))
-		return
+	m.reconcile(ctx, result.Keys)
+
+	if err := m.indexer.ClearCheckpoint(ctx); err != nil {
+		m.addLog("warning", fmt.Sprintf("Failed to clear sync checkpoint: %v", err))
 	}
 
 	duration := time.Since(startTime)
@@ -134,8 +212,112 @@ func (m *Manager) RunSync(ctx context.Context) {
 	m.status.Duration = duration.Round(time.Second).String()
 	m.mu.Unlock()
 
-	m.addLog("info", fmt.Sprintf("Sync completed: %d issues, %d comments in %s",
-		result.TotalIssues, result.TotalComments, duration.Round(time.Second)))
+	resumed := ""
+	if result.Resumed > 0 {
+		resumed = fmt.Sprintf(", resumed from %d/%d", result.Resumed, result.TotalIssues)
+	}
+	m.addLog("info", fmt.Sprintf("Sync completed: %d issues, %d comments, %d attachments (%d with text), %d worklog entries in %s%s",
+		result.TotalIssues, result.TotalComments, result.TotalAttachments, result.ExtractedAttachments,
+		result.TotalWorklog, duration.Round(time.Second), resumed))
+}
+
+// setProgress - updates the progress of the running sync
+func (m *Manager) setProgress(p tracker.SyncProgress) {
+	m.mu.Lock()
+	started := m.status.Phase != tracker.PhaseRecent && p.Phase == tracker.PhaseRecent
+	listed := m.status.Phase == tracker.PhaseListing && p.Phase != tracker.PhaseListing
+	backfill := m.status.Phase == tracker.PhaseRecent && p.Phase == tracker.PhaseBackfill
+	m.status.Processed, m.status.Total, m.status.ResumedFrom = p.Processed, p.Total, p.Resumed
+	m.status.Phase = p.Phase
+	m.status.Recent, m.status.Backfill = p.Recent, p.Backfill
+	m.status.Partitions = p.Partitions
+	m.mu.Unlock()
+
+	if listed {
+		m.addLog("info", fmt.Sprintf("Listed %d issues in %d partitions", p.Total, len(p.Partitions)))
+	}
+	if started && p.Resumed > 0 {
+		m.addLog("info", fmt.Sprintf("Resuming sync from %d/%d issues", p.Resumed, p.Total))
+	}
+	if backfill && p.Backfill.Total > 0 {
+		m.addLog("info", fmt.Sprintf("Recent issues synced and searchable: %d; backfilling %d older issues",
+			p.Recent.Total, p.Backfill.Total-p.Backfill.Processed))
+	}
+}
+
+// SetMaxDeletePercent - sets the share of indexed issues that reconciliation
+// removes without confirmation
+func (m *Manager) SetMaxDeletePercent(percent int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxDeletePercent = percent
+}
+
+// reconcile - removes indexed issues missing among the listed ones, or keeps them
+// for confirmation if there are too many
+func (m *Manager) reconcile(ctx context.Context, keys []string) {
+	m.mu.RLock()
+	opts := indexer.ReconcileOptions{MaxDeletePercent: m.maxDeletePercent}
+	m.mu.RUnlock()
+
+	rec, err := m.indexer.Reconcile(ctx, keys, opts)
+	if err != nil {
+		m.addLog("warning", fmt.Sprintf("Reconciliation failed: %v", err))
+		return
+	}
+
+	m.mu.Lock()
+	m.pendingDeletions = nil
+	if rec.Blocked {
+		m.pendingDeletions = rec.Stale
+	}
+	m.status.PendingDeletions = len(m.pendingDeletions)
+	m.mu.Unlock()
+
+	switch {
+	case rec.Blocked:
+		m.addLog("warning", fmt.Sprintf("Reconciliation: %d of %d indexed issues (%.0f%%) are missing in Tracker, above the %d%% threshold; kept until removal is confirmed: %s",
+			len(rec.Stale), rec.Indexed, rec.Percent(), opts.MaxDeletePercent, keySample(rec.Stale)))
+	case rec.Removed > 0:
+		m.addLog("info", fmt.Sprintf("Reconciliation: removed %d issues missing in Tracker: %s",
+			rec.Removed, keySample(rec.Stale)))
+	}
+}
+
+// ConfirmDeletions - removes stale issues kept by reconciliation because of the threshold
+func (m *Manager) ConfirmDeletions(ctx context.Context) error {
+	if m.GetStatus().InProgress {
+		return ErrSyncInProgress
+	}
+
+	m.mu.RLock()
+	keys := m.pendingDeletions
+	m.mu.RUnlock()
+	if len(keys) == 0 {
+		return ErrNoPendingDeletions
+	}
+
+	if err := m.indexer.RemoveIssues(ctx, keys); err != nil {
+		m.addLog("error", fmt.Sprintf("Removal of stale issues failed: %v", err))
+		return err
+	}
+
+	m.mu.Lock()
+	m.pendingDeletions = nil
+	m.status.PendingDeletions = 0
+	m.mu.Unlock()
+
+	m.addLog("info", fmt.Sprintf("Removed %d issues missing in Tracker, confirmed by user: %s", len(keys), keySample(keys)))
+	return nil
+}
+
+// keySample - lists the first keys for the log
+func keySample(keys []string) string {
+	const limit = 20
+	if len(keys) <= limit {
+		return strings.Join(keys, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(keys[:limit], ", "), len(keys)-limit)
 }
 
 // TriggerSync - starts synchronization manually
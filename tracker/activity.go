@@ -0,0 +1,33 @@
+package tracker
+
+import "time"
+
+// IndexedUser - follower of an issue prepared for indexing
+type IndexedUser struct {
+	ID    string `json:"id"`
+	Login string `json:"login,omitempty"`
+	Name  string `json:"name"`
+}
+
+// convertFollowers - converts followers keeping their order
+func convertFollowers(users []UserRef) []IndexedUser {
+	if len(users) == 0 {
+		return nil
+	}
+	indexed := make([]IndexedUser, 0, len(users))
+	for _, u := range users {
+		indexed = append(indexed, IndexedUser{ID: u.ID, Login: u.Login, Name: u.Display})
+	}
+	return indexed
+}
+
+// lastCommented - returns the time of the latest comment, zero if there are none
+func lastCommented(comments []Comment) time.Time {
+	var last time.Time
+	for _, c := range comments {
+		if c.CreatedAt.After(last) {
+			last = c.CreatedAt.Time
+		}
+	}
+	return last
+}
@@ -0,0 +1,199 @@
+package tracker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ytbs/extract"
+)
+
+// defaultAttachmentWorkers - concurrent downloads if not configured
+const defaultAttachmentWorkers = 2
+
+// AttachmentOptions - attachment download parameters
+type AttachmentOptions struct {
+	// MaxSize - files up to this size in bytes are downloaded for text extraction,
+	// zero disables downloads and only names are indexed
+	MaxSize int64
+	// Workers - number of concurrent downloads, shared by all issues
+	Workers int
+	// Cache - previously extracted texts, optional
+	Cache AttachmentCache
+}
+
+// AttachmentCache - storage of extracted attachment texts
+type AttachmentCache interface {
+	// AttachmentByID - returns the content hash and text of a processed attachment.
+	// Attachments are immutable, so a known ID is never downloaded again
+	AttachmentByID(ctx context.Context, id string) (hash, text string, ok bool, err error)
+	// AttachmentTextByHash - returns text extracted from a file with the same contents
+	AttachmentTextByHash(ctx context.Context, hash string) (text string, ok bool, err error)
+}
+
+// IndexedAttachment - attachment prepared for indexing
+type IndexedAttachment struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Mimetype   string    `json:"mimetype,omitempty"`
+	Size       int64     `json:"size"`
+	Author     string    `json:"author"`
+	AuthorName string    `json:"author_name"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// ContentHash - SHA-256 of the contents, empty if the file was not downloaded
+	ContentHash string `json:"content_hash,omitempty"`
+	// Text - extracted text, not loaded back from the index
+	Text string `json:"-"`
+}
+
+// FetchIssueAttachments - loads the list of files attached to the specified issue
+func (c *Client) FetchIssueAttachments(ctx context.Context, issueKey string) ([]Attachment, error) {
+	respBody, _, err := c.doRequest(ctx, "GET", fmt.Sprintf("/issues/%s/attachments", issueKey), nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch attachments for %s: %w", issueKey, err)
+	}
+
+	var attachments []Attachment
+	if err := json.Unmarshal(respBody, &attachments); err != nil {
+		return nil, fmt.Errorf("unmarshal attachments: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// DownloadAttachment - downloads attachment contents, failing with ErrTooLarge above maxSize bytes
+func (c *Client) DownloadAttachment(ctx context.Context, attachment Attachment, maxSize int64) ([]byte, error) {
+	data, _, err := c.doRequestURL(ctx, "GET", attachment.Content, nil, maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("download attachment %s: %w", attachment.Name, err)
+	}
+	return data, nil
+}
+
+// attachmentLoader - downloads attachments and extracts their texts during one sync
+type attachmentLoader struct {
+	client    *Client
+	opts      AttachmentOptions
+	downloads chan struct{}
+
+	mu     sync.Mutex
+	byHash map[string]string
+}
+
+// newAttachmentLoader - creates a loader with its own download concurrency limit
+func newAttachmentLoader(c *Client, opts AttachmentOptions) *attachmentLoader {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultAttachmentWorkers
+	}
+	return &attachmentLoader{
+		client:    c,
+		opts:      opts,
+		downloads: make(chan struct{}, workers),
+		byHash:    make(map[string]string),
+	}
+}
+
+// load - converts attachments of an issue, extracting texts of files under the size limit.
+// Failed files are indexed by name only and reported in the returned errors
+func (l *attachmentLoader) load(ctx context.Context, issueKey string, attachments []Attachment) ([]IndexedAttachment, []error) {
+	var errs []error
+	indexed := make([]IndexedAttachment, 0, len(attachments))
+
+	for _, a := range attachments {
+		ia := IndexedAttachment{
+			ID:         a.ID,
+			Name:       a.Name,
+			Mimetype:   a.Mimetype,
+			Size:       a.Size,
+			Author:     a.CreatedBy.ID,
+			AuthorName: a.CreatedBy.Display,
+			CreatedAt:  a.CreatedAt.Time,
+		}
+
+		if err := l.text(ctx, a, &ia); err != nil {
+			errs = append(errs, fmt.Errorf("attachment %s of %s: %w", a.Name, issueKey, err))
+		}
+		indexed = append(indexed, ia)
+	}
+
+	return indexed, errs
+}
+
+// text - fills the content hash and text of an attachment from the cache or the downloaded file
+func (l *attachmentLoader) text(ctx context.Context, a Attachment, ia *IndexedAttachment) error {
+	if l.opts.Cache != nil {
+		hash, text, ok, err := l.opts.Cache.AttachmentByID(ctx, a.ID)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ia.ContentHash, ia.Text = hash, text
+			return nil
+		}
+	}
+
+	if l.opts.MaxSize <= 0 || a.Size > l.opts.MaxSize || a.Content == "" || !extract.Supported(a.Name, a.Mimetype) {
+		return nil
+	}
+
+	select {
+	case l.downloads <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	data, err := l.client.DownloadAttachment(ctx, a, l.opts.MaxSize)
+	<-l.downloads
+	if errors.Is(err, ErrTooLarge) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	ia.ContentHash = hex.EncodeToString(sum[:])
+
+	l.mu.Lock()
+	text, ok := l.byHash[ia.ContentHash]
+	l.mu.Unlock()
+	if ok {
+		ia.Text = text
+		return nil
+	}
+
+	if l.opts.Cache != nil {
+		text, ok, err := l.opts.Cache.AttachmentTextByHash(ctx, ia.ContentHash)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ia.Text = text
+			l.remember(ia.ContentHash, text)
+			return nil
+		}
+	}
+
+	text, err = extract.Text(a.Name, a.Mimetype, data)
+	if err != nil {
+		// the hash is kept, so the file is not downloaded again
+		log.Printf("No text extracted from attachment %s: %v", a.Name, err)
+	}
+	ia.Text = text
+	l.remember(ia.ContentHash, text)
+	return nil
+}
+
+// remember - caches extracted text for files with the same contents in this sync
+func (l *attachmentLoader) remember(hash, text string) {
+	l.mu.Lock()
+	l.byHash[hash] = text
+	l.mu.Unlock()
+}
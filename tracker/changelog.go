@@ -0,0 +1,95 @@
+package tracker
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// changelog field IDs used by history filters
+const (
+	FieldStatus   = "status"
+	FieldAssignee = "assignee"
+)
+
+// IndexedChange - change of a single field prepared for indexing.
+// Values keep keys (or IDs) for filtering and display names for showing
+type IndexedChange struct {
+	ChangeID    string    `json:"change_id"`
+	Type        string    `json:"type"`
+	Field       string    `json:"field"`
+	FieldName   string    `json:"field_name"`
+	From        string    `json:"from,omitempty"`
+	FromDisplay string    `json:"from_display,omitempty"`
+	To          string    `json:"to,omitempty"`
+	ToDisplay   string    `json:"to_display,omitempty"`
+	Author      string    `json:"author"`
+	AuthorName  string    `json:"author_name"`
+	ChangedAt   time.Time `json:"changed_at"`
+}
+
+// convertChangelog - flattens changelog entries to field changes
+func convertChangelog(entries []ChangelogEntry) []IndexedChange {
+	var changes []IndexedChange
+	for _, e := range entries {
+		for _, f := range e.Fields {
+			change := IndexedChange{
+				ChangeID:   e.ID,
+				Type:       e.Type,
+				Field:      f.Field.ID,
+				FieldName:  f.Field.Display,
+				Author:     e.UpdatedBy.ID,
+				AuthorName: e.UpdatedBy.Display,
+				ChangedAt:  e.UpdatedAt.Time,
+			}
+			change.From, change.FromDisplay = fieldValue(f.From)
+			change.To, change.ToDisplay = fieldValue(f.To)
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// fieldValue - returns the key and display name of a changed field value
+func fieldValue(raw json.RawMessage) (string, string) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", ""
+	}
+
+	var ref struct {
+		ID      json.RawMessage `json:"id"`
+		Key     string          `json:"key"`
+		Login   string          `json:"login"`
+		Display string          `json:"display"`
+	}
+	var list []json.RawMessage
+	var str string
+
+	switch {
+	case json.Unmarshal(raw, &list) == nil:
+		var keys, names []string
+		for _, item := range list {
+			key, name := fieldValue(item)
+			keys = append(keys, key)
+			names = append(names, name)
+		}
+		return strings.Join(keys, ", "), strings.Join(names, ", ")
+	case raw[0] == '{' && json.Unmarshal(raw, &ref) == nil:
+		key := ref.Key
+		if key == "" {
+			key = strings.Trim(string(ref.ID), `"`)
+		}
+		if key == "" {
+			key = ref.Login
+		}
+		if ref.Display == "" {
+			return key, key
+		}
+		return key, ref.Display
+	case json.Unmarshal(raw, &str) == nil:
+		return str, str
+	default:
+		// numbers and booleans as is
+		return string(raw), string(raw)
+	}
+}
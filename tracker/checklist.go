@@ -0,0 +1,54 @@
+package tracker
+
+import (
+	"strings"
+	"time"
+)
+
+// deadlineTypeDate - deadline on a whole day rather than an exact time
+const deadlineTypeDate = "date"
+
+// IndexedChecklistItem - checklist item prepared for indexing
+type IndexedChecklistItem struct {
+	ID           string `json:"id"`
+	Text         string `json:"text"`
+	Checked      bool   `json:"checked"`
+	Assignee     string `json:"assignee,omitempty"`
+	AssigneeName string `json:"assignee_name,omitempty"`
+	// Deadline - moment the item becomes overdue, zero if there is no deadline
+	Deadline time.Time `json:"deadline,omitempty"`
+}
+
+// Overdue - checks if the item is unchecked after its deadline
+func (i IndexedChecklistItem) Overdue(now time.Time) bool {
+	return !i.Checked && !i.Deadline.IsZero() && i.Deadline.Before(now)
+}
+
+// convertChecklist - converts checklist items keeping their order and returns their plain text
+func convertChecklist(items []ChecklistItem) ([]IndexedChecklistItem, string) {
+	var texts []string
+	indexed := make([]IndexedChecklistItem, 0, len(items))
+	for _, item := range items {
+		ci := IndexedChecklistItem{
+			ID:      item.ID,
+			Text:    ParseMarkup(item.Text).Text,
+			Checked: item.Checked,
+		}
+		if item.Assignee != nil {
+			ci.Assignee = item.Assignee.ID
+			ci.AssigneeName = item.Assignee.Display
+		}
+		if item.Deadline != nil && !item.Deadline.Date.IsZero() {
+			ci.Deadline = item.Deadline.Date.Time
+			if item.Deadline.DeadlineType == deadlineTypeDate {
+				// a date deadline lasts until the end of the day
+				ci.Deadline = ci.Deadline.AddDate(0, 0, 1).Add(-time.Second)
+			}
+		}
+		if ci.Text != "" {
+			texts = append(texts, ci.Text)
+		}
+		indexed = append(indexed, ci)
+	}
+	return indexed, strings.Join(texts, "\n")
+}
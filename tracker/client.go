@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,6 +16,9 @@ const (
 	maxPerPage = 100
 )
 
+// ErrTooLarge - response exceeds the size limit
+var ErrTooLarge = errors.New("response too large")
+
 // Client - client for Yandex Tracker API
 type Client struct {
 	httpClient *http.Client
@@ -35,6 +39,12 @@ func NewClient(token, orgID string) *Client {
 
 // doRequest - performs an HTTP request to the Tracker API
 func (c *Client) doRequest(ctx context.Context, method, path string, body any) ([]byte, http.Header, error) {
+	return c.doRequestURL(ctx, method, baseURL+path, body, -1)
+}
+
+// doRequestURL - performs an HTTP request to an absolute Tracker API URL.
+// Responses longer than maxSize bytes fail with ErrTooLarge, negative maxSize means no limit
+func (c *Client) doRequestURL(ctx context.Context, method, url string, body any, maxSize int64) ([]byte, http.Header, error) {
 	var reqBody io.Reader
 	if body != nil {
 		jsonBody, err := json.Marshal(body)
@@ -44,7 +54,7 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body any) (
 		reqBody = bytes.NewReader(jsonBody)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return nil, nil, fmt.Errorf("create request: %w", err)
 	}
@@ -59,7 +69,11 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body any) (
 	}
 	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	var bodyReader io.Reader = resp.Body
+	if maxSize >= 0 {
+		bodyReader = io.LimitReader(resp.Body, maxSize+1)
+	}
+	respBody, err := io.ReadAll(bodyReader)
 	if err != nil {
 		return nil, nil, fmt.Errorf("read response body: %w", err)
 	}
@@ -68,5 +82,9 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body any) (
 		return nil, nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
 	}
 
+	if maxSize >= 0 && int64(len(respBody)) > maxSize {
+		return nil, nil, ErrTooLarge
+	}
+
 	return respBody, resp.Header, nil
 }
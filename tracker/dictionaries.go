@@ -0,0 +1,208 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dictionary kinds, named after the issue fields they describe
+const (
+	DictPriority   = "priority"
+	DictStatus     = "status"
+	DictType       = "type"
+	DictResolution = "resolution"
+	DictQueue      = "queue"
+)
+
+// status categories shared by all workflows
+const (
+	CategoryOpen       = "open"
+	CategoryInProgress = "in_progress"
+	CategoryDone       = "done"
+)
+
+// statusCategories - categories of Tracker status types
+var statusCategories = map[string]string{
+	"new":        CategoryOpen,
+	"paused":     CategoryInProgress,
+	"inProgress": CategoryInProgress,
+	"inReview":   CategoryInProgress,
+	"onReview":   CategoryInProgress,
+	"done":       CategoryDone,
+	"cancelled":  CategoryDone,
+}
+
+// dictionaryPaths - API endpoints of the dictionaries; names are requested in all languages
+var dictionaryPaths = []struct {
+	kind  string
+	path  string
+	paged bool
+}{
+	{DictPriority, "/priorities?localized=false", false},
+	{DictStatus, "/statuses?localized=false", false},
+	{DictType, "/issuetypes?localized=false", false},
+	{DictResolution, "/resolutions?localized=false", false},
+	{DictQueue, "/queues", true},
+}
+
+// LocalizedName - name in several languages by language code.
+// Names returned as a plain string are stored under the empty code
+type LocalizedName map[string]string
+
+// UnmarshalJSON - accepts a string or an object of translations
+func (n *LocalizedName) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*n = LocalizedName{"": s}
+		return nil
+	}
+	var names map[string]string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	*n = names
+	return nil
+}
+
+// In - returns the name in the language, falling back to English, Russian and any other
+func (n LocalizedName) In(lang string) string {
+	for _, code := range []string{lang, "en", "ru", ""} {
+		if name := n[code]; name != "" {
+			return name
+		}
+	}
+	for _, name := range n {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// Has - checks if the name in any language equals the value, ignoring case
+func (n LocalizedName) Has(value string) bool {
+	for _, name := range n {
+		if strings.EqualFold(name, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// dictionaryItem - entry of any dictionary endpoint
+type dictionaryItem struct {
+	ID    json.RawMessage `json:"id"`
+	Key   string          `json:"key"`
+	Name  LocalizedName   `json:"name"`
+	Order int             `json:"order"`
+	// Type - status type, e.g. new, inProgress or done
+	Type string `json:"type"`
+
+	// Queue - queue of a component or version
+	Queue *QueueRef `json:"queue"`
+	// Board, Status, StartDate, EndDate - board, state and dates of a sprint
+	Board     *boardRef    `json:"board"`
+	Status    string       `json:"status"`
+	StartDate *TrackerDate `json:"startDate"`
+	EndDate   *TrackerDate `json:"endDate"`
+}
+
+// DictionaryEntry - value of an issue field with localized names
+type DictionaryEntry struct {
+	Kind  string        `json:"kind"`
+	Key   string        `json:"key"`
+	ID    string        `json:"id"`
+	Names LocalizedName `json:"names"`
+	// Order - sort order, e.g. priorities from the lowest
+	Order int `json:"order"`
+	// Category - open, in_progress or done, for statuses only
+	Category string `json:"category,omitempty"`
+
+	// Parent - queue of a component or version, board of a sprint
+	Parent string `json:"parent,omitempty"`
+	// Status, Start, End - state and dates of a sprint, e.g. in_progress
+	Status string    `json:"status,omitempty"`
+	Start  time.Time `json:"start,omitempty"`
+	End    time.Time `json:"end,omitempty"`
+}
+
+// StatusCategory - returns the category of a Tracker status type, open if unknown
+func StatusCategory(statusType string) string {
+	if category, ok := statusCategories[statusType]; ok {
+		return category
+	}
+	return CategoryOpen
+}
+
+// FetchDictionaries - loads priorities, statuses, issue types, resolutions, queues
+// and the planning catalog: components, versions, boards and sprints
+func (c *Client) FetchDictionaries(ctx context.Context) ([]DictionaryEntry, error) {
+	var entries []DictionaryEntry
+	var queues []string
+	for _, d := range dictionaryPaths {
+		items, err := c.fetchDictionary(ctx, d.path, d.paged)
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s dictionary: %w", d.kind, err)
+		}
+
+		for _, item := range items {
+			entry := DictionaryEntry{
+				Kind:  d.kind,
+				Key:   item.Key,
+				ID:    strings.Trim(string(item.ID), `"`),
+				Names: item.Name,
+				Order: item.Order,
+			}
+			if d.kind == DictStatus {
+				entry.Category = StatusCategory(item.Type)
+			}
+			if d.kind == DictQueue {
+				queues = append(queues, item.Key)
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	planning, err := c.fetchPlanning(ctx, queues)
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, planning...), nil
+}
+
+// fetchDictionary - loads all items of a dictionary endpoint
+func (c *Client) fetchDictionary(ctx context.Context, path string, paged bool) ([]dictionaryItem, error) {
+	if !paged {
+		respBody, _, err := c.doRequest(ctx, "GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+		var items []dictionaryItem
+		if err := json.Unmarshal(respBody, &items); err != nil {
+			return nil, fmt.Errorf("unmarshal dictionary: %w", err)
+		}
+		return items, nil
+	}
+
+	var all []dictionaryItem
+	for page := 1; ; page++ {
+		respBody, headers, err := c.doRequest(ctx, "GET", fmt.Sprintf("%s?perPage=%d&page=%d", path, maxPerPage, page), nil)
+		if err != nil {
+			return all, err
+		}
+		var items []dictionaryItem
+		if err := json.Unmarshal(respBody, &items); err != nil {
+			return all, fmt.Errorf("unmarshal dictionary: %w", err)
+		}
+		all = append(all, items...)
+
+		totalPages, err := strconv.Atoi(headers.Get("X-Total-Pages"))
+		if err != nil || page >= totalPages {
+			return all, nil
+		}
+	}
+}
@@ -5,24 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/url"
 	"strconv"
 )
 
-// FetchAllIssues - loads all issues from the specified queues (or all if queues is empty).
-// Uses a scrolling mechanism for large datasets
-func (c *Client) FetchAllIssues(ctx context.Context, queues []string) ([]Issue, error) {
+// fetchScroll - loads all issues matching the query page by page with a scroll,
+// reporting the number of loaded issues after each page
+func (c *Client) fetchScroll(ctx context.Context, query string, onPage func(fetched int)) ([]Issue, error) {
 	var allIssues []Issue
 
-	var query string
-	if len(queues) > 0 {
-		query = fmt.Sprintf(`Queue: %s "Sort By": Updated DESC`, queues[0])
-		for _, q := range queues[1:] {
-			query = fmt.Sprintf(`(%s) OR Queue: %s`, query, q)
-		}
-	} else {
-		query = `"Sort By": Updated DESC`
-	}
-
 	reqBody := SearchRequest{Query: query}
 
 	// first request with scroll initialization
@@ -49,6 +40,9 @@ func (c *Client) FetchAllIssues(ctx context.Context, queues []string) ([]Issue,
 		}
 
 		allIssues = append(allIssues, issues...)
+		if onPage != nil {
+			onPage(len(allIssues))
+		}
 
 		// check for more pages
 		scrollID := headers.Get("X-Scroll-Id")
@@ -60,7 +54,6 @@ func (c *Client) FetchAllIssues(ctx context.Context, queues []string) ([]Issue,
 		page++
 	}
 
-	log.Printf("Total issues fetched: %d", len(allIssues))
 	return allIssues, nil
 }
 
@@ -110,6 +103,60 @@ func (c *Client) FetchIssueComments(ctx context.Context, issueKey string) ([]Com
 	return allComments, nil
 }
 
+// FetchIssueLinks - loads links of the specified issue to other issues
+func (c *Client) FetchIssueLinks(ctx context.Context, issueKey string) ([]IssueLink, error) {
+	respBody, _, err := c.doRequest(ctx, "GET", fmt.Sprintf("/issues/%s/links", issueKey), nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch links for %s: %w", issueKey, err)
+	}
+
+	var links []IssueLink
+	if err := json.Unmarshal(respBody, &links); err != nil {
+		return nil, fmt.Errorf("unmarshal links: %w", err)
+	}
+
+	return links, nil
+}
+
+// FetchIssueChangelog - loads changelog entries of the specified issue following the entry afterID,
+// or the whole changelog if afterID is empty
+func (c *Client) FetchIssueChangelog(ctx context.Context, issueKey, afterID string) ([]ChangelogEntry, error) {
+	var allEntries []ChangelogEntry
+
+	for {
+		select {
+		case <-ctx.Done():
+			return allEntries, ctx.Err()
+		default:
+		}
+
+		path := fmt.Sprintf("/issues/%s/changelog?perPage=%d", issueKey, maxPerPage)
+		if afterID != "" {
+			path += "&id=" + url.QueryEscape(afterID)
+		}
+
+		respBody, _, err := c.doRequest(ctx, "GET", path, nil)
+		if err != nil {
+			return allEntries, fmt.Errorf("fetch changelog for %s: %w", issueKey, err)
+		}
+
+		var entries []ChangelogEntry
+		if err := json.Unmarshal(respBody, &entries); err != nil {
+			return allEntries, fmt.Errorf("unmarshal changelog: %w", err)
+		}
+
+		allEntries = append(allEntries, entries...)
+
+		// the changelog is paged by the ID of the last received entry
+		if len(entries) < maxPerPage {
+			break
+		}
+		afterID = entries[len(entries)-1].ID
+	}
+
+	return allEntries, nil
+}
+
 // FetchUpdatedIssues - loads issues updated since the specified timestamp (in RFC3339 format)
 func (c *Client) FetchUpdatedIssues(ctx context.Context, since string) ([]Issue, error) {
 	query := fmt.Sprintf(`Updated: >= "%s" "Sort By": Updated ASC`, since)
@@ -0,0 +1,43 @@
+package tracker
+
+// hierarchyParent - next issue up the hierarchy: the parent issue, or the epic for top level issues
+func hierarchyParent(issue IndexedIssue) string {
+	if issue.Parent != "" {
+		return issue.Parent
+	}
+	return issue.Epic
+}
+
+// listedParents - next issues up the hierarchy of the listed issues, so that ancestors
+// are resolved before details of the issues are loaded
+func listedParents(issues []Issue) map[string]string {
+	parents := make(map[string]string, len(issues))
+	for _, issue := range issues {
+		var indexed IndexedIssue
+		if issue.Parent != nil {
+			indexed.Parent = issue.Parent.Key
+		}
+		if issue.Epic != nil {
+			indexed.Epic = issue.Epic.Key
+		}
+		parents[issue.Key] = hierarchyParent(indexed)
+	}
+	return parents
+}
+
+// ancestorPath - keys of the ancestors of an issue from the hierarchy root down to the direct parent
+func ancestorPath(parents map[string]string, key string) []string {
+	var path []string
+	seen := map[string]bool{key: true}
+	for key := parents[key]; key != "" && !seen[key]; key = parents[key] {
+		// cycles are possible with inconsistent parent and epic fields
+		seen[key] = true
+		path = append(path, key)
+	}
+
+	// root first
+	for l, r := 0, len(path)-1; l < r; l, r = l+1, r-1 {
+		path[l], path[r] = path[r], path[l]
+	}
+	return path
+}
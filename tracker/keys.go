@@ -0,0 +1,31 @@
+package tracker
+
+import "strings"
+
+// FieldKey - changelog field of the issue key, changed when the issue moves to another queue
+const FieldKey = "key"
+
+// previousKeys - returns keys the issue was known under before: aliases and keys it had
+// before moves between queues
+func previousKeys(issue Issue, changes []ChangelogEntry) []string {
+	var keys []string
+	for _, alias := range issue.Aliases {
+		keys = append(keys, strings.TrimSpace(alias))
+	}
+	for _, c := range convertChangelog(changes) {
+		if c.Field == FieldKey {
+			keys = append(keys, c.From)
+		}
+	}
+
+	result := keys[:0]
+	for _, key := range uniqueStrings(keys) {
+		if key != "" && key != issue.Key {
+			result = append(result, key)
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
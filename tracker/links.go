@@ -0,0 +1,58 @@
+package tracker
+
+// link relations - link type and direction as seen from the linking issue
+const (
+	RelationRelates      = "relates"
+	RelationDependsOn    = "depends_on"
+	RelationBlocks       = "blocks"
+	RelationDuplicates   = "duplicates"
+	RelationDuplicatedBy = "duplicated_by"
+	RelationSubtaskOf    = "subtask_of"
+	RelationParentOf     = "parent_of"
+	RelationHasEpic      = "has_epic"
+	RelationEpicOf       = "epic_of"
+)
+
+// linkRelations - relations by link type ID and direction
+var linkRelations = map[string][2]string{
+	// type: {outward, inward}
+	"relates":    {RelationRelates, RelationRelates},
+	"depends":    {RelationDependsOn, RelationBlocks},
+	"duplicates": {RelationDuplicates, RelationDuplicatedBy},
+	"subtask":    {RelationSubtaskOf, RelationParentOf},
+	"epic":       {RelationHasEpic, RelationEpicOf},
+}
+
+// inverseRelations - relation as seen from the linked issue
+var inverseRelations = map[string]string{
+	RelationRelates:      RelationRelates,
+	RelationDependsOn:    RelationBlocks,
+	RelationBlocks:       RelationDependsOn,
+	RelationDuplicates:   RelationDuplicatedBy,
+	RelationDuplicatedBy: RelationDuplicates,
+	RelationSubtaskOf:    RelationParentOf,
+	RelationParentOf:     RelationSubtaskOf,
+	RelationHasEpic:      RelationEpicOf,
+	RelationEpicOf:       RelationHasEpic,
+}
+
+// LinkRelation - returns relation name for a link type and direction.
+// Unknown link types are returned as is
+func LinkRelation(linkType, direction string) string {
+	relations, ok := linkRelations[linkType]
+	if !ok {
+		return linkType
+	}
+	if direction == "inward" {
+		return relations[1]
+	}
+	return relations[0]
+}
+
+// InverseRelation - returns the relation as seen from the other side of the link
+func InverseRelation(relation string) string {
+	if inverse, ok := inverseRelations[relation]; ok {
+		return inverse
+	}
+	return relation
+}
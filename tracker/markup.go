@@ -0,0 +1,152 @@
+package tracker
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Markup - searchable text and references extracted from Tracker markup (YFM or HTML)
+type Markup struct {
+	// Text - plain text without markup, link URLs and code blocks
+	Text string
+	// Code - contents of fenced code blocks
+	Code string
+	// Links - URLs of links, in order of appearance
+	Links []string
+	// Mentions - logins of @mentioned users
+	Mentions []string
+	// IssueKeys - referenced issue keys
+	IssueKeys []string
+}
+
+var (
+	fenceRe       = regexp.MustCompile("^\\s*(```|~~~)")
+	htmlBreakRe   = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/tr|/h[1-6])\s*/?>`)
+	htmlTagRe     = regexp.MustCompile(`(?i)</?(?:a|abbr|b|blockquote|br|caption|code|col|colgroup|dd|del|details|div|dl|dt|em|font|h[1-6]|hr|i|img|ins|kbd|li|mark|ol|p|pre|s|small|span|strike|strong|sub|summary|sup|table|tbody|td|tfoot|th|thead|tr|tt|u|ul)(?:\s[^<>]*)?/?>`)
+	yfmCutRe      = regexp.MustCompile(`\{%\s*(?:cut|note\s+\w+)\s+"([^"]*)"\s*%\}`)
+	yfmTagRe      = regexp.MustCompile(`\{%[^%]*%\}`)
+	imageRe       = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]*)[^)]*\)(\{[^}]*\})?`)
+	mdLinkRe      = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]+)[^)]*\)(\{[^}]*\})?`)
+	wikiLinkRe    = regexp.MustCompile(`\(\((\S+)\s*([^)]*)\)\)`)
+	autoLinkRe    = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	bareURLRe     = regexp.MustCompile(`https?://[^\s<>()\[\]"']+`)
+	tableRuleRe   = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	wikiTableRe   = regexp.MustCompile(`#\||\|#|\|\|`)
+	headingMarkRe = regexp.MustCompile(`^\s*#{1,6}\s+`)
+	listMarkRe    = regexp.MustCompile(`^\s*(?:>\s*)*(?:[-*+]|\d+[.)])?\s+`)
+	emphasisRe    = regexp.MustCompile(`\*\*|~~|==`)
+	mentionRe     = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9][A-Za-z0-9._-]*[A-Za-z0-9])`)
+	issueKeyRe    = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-\d+\b`)
+	spacesRe      = regexp.MustCompile(`[ \t\p{Zs}]+`)
+)
+
+// ParseMarkup - converts Tracker markup (YFM markdown, wiki tables, HTML) to searchable text
+// and collects links, mentions and issue keys found in it
+func ParseMarkup(src string) Markup {
+	var m Markup
+	if strings.TrimSpace(src) == "" {
+		return m
+	}
+
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+
+	// code blocks go to a separate field
+	var text, code []string
+	inCode := false
+	for _, line := range strings.Split(src, "\n") {
+		if fenceRe.MatchString(line) {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			code = append(code, line)
+		} else {
+			text = append(text, line)
+		}
+	}
+	m.Code = strings.TrimSpace(strings.Join(code, "\n"))
+	s := strings.Join(text, "\n")
+
+	// autolinks look like tags, so they are collected before tags are dropped
+	s = autoLinkRe.ReplaceAllStringFunc(s, func(link string) string {
+		m.Links = append(m.Links, autoLinkRe.FindStringSubmatch(link)[1])
+		return " "
+	})
+
+	// HTML: keep line structure, drop tags, decode entities
+	s = htmlBreakRe.ReplaceAllString(s, "\n")
+	s = htmlTagRe.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+
+	// YFM blocks: keep cut/note titles, drop the rest of {% ... %} tags
+	s = yfmCutRe.ReplaceAllString(s, "$1")
+	s = yfmTagRe.ReplaceAllString(s, " ")
+
+	// links: keep visible text, collect URLs
+	s = imageRe.ReplaceAllString(s, "$1")
+	s = mdLinkRe.ReplaceAllStringFunc(s, func(link string) string {
+		parts := mdLinkRe.FindStringSubmatch(link)
+		m.Links = append(m.Links, parts[2])
+		return parts[1]
+	})
+	s = wikiLinkRe.ReplaceAllStringFunc(s, func(link string) string {
+		parts := wikiLinkRe.FindStringSubmatch(link)
+		m.Links = append(m.Links, parts[1])
+		return parts[2]
+	})
+	s = bareURLRe.ReplaceAllStringFunc(s, func(link string) string {
+		m.Links = append(m.Links, strings.TrimRight(link, ".,;:!?"))
+		return " "
+	})
+
+	// line level markup: tables, headings, lists, quotes
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if tableRuleRe.MatchString(line) && strings.Contains(line, "-") {
+			continue
+		}
+		line = wikiTableRe.ReplaceAllString(line, " ")
+		line = strings.ReplaceAll(line, "|", " ")
+		line = headingMarkRe.ReplaceAllString(line, "")
+		line = listMarkRe.ReplaceAllString(line, "")
+		line = emphasisRe.ReplaceAllString(line, "")
+		line = strings.ReplaceAll(line, "`", "")
+		line = strings.TrimSpace(spacesRe.ReplaceAllString(line, " "))
+
+		if line == "" && (len(lines) == 0 || lines[len(lines)-1] == "") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	m.Text = strings.TrimSpace(strings.Join(lines, "\n"))
+
+	for _, match := range mentionRe.FindAllStringSubmatch(m.Text, -1) {
+		m.Mentions = append(m.Mentions, match[1])
+	}
+
+	m.IssueKeys = issueKeyRe.FindAllString(m.Text+"\n"+m.Code+"\n"+strings.Join(m.Links, "\n"), -1)
+
+	m.Links = uniqueStrings(m.Links)
+	m.Mentions = uniqueStrings(m.Mentions)
+	m.IssueKeys = uniqueStrings(m.IssueKeys)
+
+	return m
+}
+
+// uniqueStrings - removes duplicates preserving order
+func uniqueStrings(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(values))
+	result := values[:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,109 @@
+package tracker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMarkup(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want Markup
+	}{
+		{
+			name: "empty",
+			src:  " \n ",
+			want: Markup{},
+		},
+		{
+			name: "fences",
+			src:  "Run it:\n```go\nfmt.Println(\"TEST-1\")\n```\n~~~\nmake\n~~~\ndone",
+			want: Markup{
+				Text:      "Run it:\ndone",
+				Code:      "fmt.Println(\"TEST-1\")\nmake",
+				IssueKeys: []string{"TEST-1"},
+			},
+		},
+		{
+			name: "cut and note",
+			src:  "{% cut \"Details\" %}\nhidden text\n{% endcut %}\n{% note warning \"Careful\" %}\nnote text\n{% endnote %}",
+			want: Markup{Text: "Details\nhidden text\n\nCareful\nnote text"},
+		},
+		{
+			name: "wiki table",
+			src:  "#|\n|| Key | Status ||\n|| OPS-1 | **Open** ||\n|#",
+			want: Markup{
+				Text:      "Key Status\nOPS-1 Open",
+				IssueKeys: []string{"OPS-1"},
+			},
+		},
+		{
+			name: "markdown table",
+			src:  "| Key | Status |\n|:---|---:|\n| OPS-2 | Closed |",
+			want: Markup{
+				Text:      "Key Status\nOPS-2 Closed",
+				IssueKeys: []string{"OPS-2"},
+			},
+		},
+		{
+			name: "links",
+			src:  "See [the docs](https://docs.example.com/a \"title\"){target=_blank} and ((https://wiki.example.com/page Wiki page)) ![logo](https://example.com/logo.png =100x)",
+			want: Markup{
+				Text:  "See the docs and Wiki page logo",
+				Links: []string{"https://docs.example.com/a", "https://wiki.example.com/page"},
+			},
+		},
+		{
+			name: "autolinks",
+			src:  "Mail <mailto:ops@example.com> or open <https://example.com/TEST-5>.",
+			want: Markup{
+				Text:      "Mail or open .",
+				Links:     []string{"mailto:ops@example.com", "https://example.com/TEST-5"},
+				IssueKeys: []string{"TEST-5"},
+			},
+		},
+		{
+			name: "bare URLs",
+			src:  "Logs at https://logs.example.com/q?id=1, mirror https://mirror.example.com/x.",
+			want: Markup{
+				Text:  "Logs at mirror",
+				Links: []string{"https://logs.example.com/q?id=1", "https://mirror.example.com/x"},
+			},
+		},
+		{
+			name: "html",
+			src:  "<p>First &amp; <b>bold</b></p><div>Second<br/>third</div>",
+			want: Markup{Text: "First & bold\nSecond\nthird"},
+		},
+		{
+			name: "comparisons are not tags",
+			src:  "x < y > z and a <b> c",
+			want: Markup{Text: "x < y > z and a c"},
+		},
+		{
+			name: "mentions",
+			src:  "@alice please ask @bob.smith, not mail@example.com",
+			want: Markup{
+				Text:     "@alice please ask @bob.smith, not mail@example.com",
+				Mentions: []string{"alice", "bob.smith"},
+			},
+		},
+		{
+			name: "issue keys",
+			src:  "# Duplicates TEST-12\n- OPS-3, TEST-12\n- not a key: test-4, X-1, TEST-",
+			want: Markup{
+				Text:      "Duplicates TEST-12\nOPS-3, TEST-12\nnot a key: test-4, X-1, TEST-",
+				IssueKeys: []string{"TEST-12", "OPS-3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseMarkup(tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMarkup(%q)\n got %+v\nwant %+v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// firstPartitionYear - issues created before this year are listed in one partition
+	firstPartitionYear = 2016
+	// defaultFetchWorkers - concurrent scrolls if not set in the options
+	defaultFetchWorkers = 4
+)
+
+// IssuePartition - part of the issues listed with its own scroll
+type IssuePartition struct {
+	Name  string
+	Query string
+}
+
+// PartitionProgress - listing progress of a partition
+type PartitionProgress struct {
+	Name    string `json:"name"`
+	Fetched int    `json:"fetched"`
+	Done    bool   `json:"done"`
+	Error   string `json:"error,omitempty"`
+}
+
+// issuePartitions - one partition per queue, or per creation year if all queues are synced,
+// so that issues of queues created later are not missed
+func issuePartitions(queues []string, now time.Time) []IssuePartition {
+	var partitions []IssuePartition
+	if len(queues) > 0 {
+		for _, q := range queues {
+			partitions = append(partitions, IssuePartition{
+				Name:  q,
+				Query: fmt.Sprintf(`Queue: %s "Sort By": Updated DESC`, q),
+			})
+		}
+		return partitions
+	}
+
+	partitions = append(partitions, IssuePartition{
+		Name:  fmt.Sprintf("< %d", firstPartitionYear),
+		Query: fmt.Sprintf(`Created: < "%d-01-01" "Sort By": Updated DESC`, firstPartitionYear),
+	})
+	for year := firstPartitionYear; year < now.Year(); year++ {
+		partitions = append(partitions, IssuePartition{
+			Name:  fmt.Sprintf("%d", year),
+			Query: fmt.Sprintf(`Created: "%d-01-01".."%d-12-31" "Sort By": Updated DESC`, year, year),
+		})
+	}
+	// the last partition is open, also for issues created while listing
+	partitions = append(partitions, IssuePartition{
+		Name:  fmt.Sprintf(">= %d", now.Year()),
+		Query: fmt.Sprintf(`Created: >= "%d-01-01" "Sort By": Updated DESC`, now.Year()),
+	})
+	return partitions
+}
+
+// FetchPartitioned - lists issues of the partitions with one scroll per partition, at most workers at once.
+// Issues listed in several partitions, e.g. moved between queues meanwhile, are kept once in their latest state;
+// the result is ordered by update time, latest first. Issues of failed partitions are left out
+// and the failures are reported in the progress, the error is returned only if the context is done
+func (c *Client) FetchPartitioned(ctx context.Context, partitions []IssuePartition, workers int,
+	progress func([]PartitionProgress)) ([]Issue, []PartitionProgress, error) {
+	if workers <= 0 {
+		workers = defaultFetchWorkers
+	}
+
+	var mu sync.Mutex
+	states := make([]PartitionProgress, len(partitions))
+	for i, p := range partitions {
+		states[i].Name = p.Name
+	}
+	// report - passes a copy of the progress, called under the lock
+	report := func() {
+		if progress != nil {
+			progress(append([]PartitionProgress(nil), states...))
+		}
+	}
+
+	listed := make([][]Issue, len(partitions))
+	budget := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, p := range partitions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case budget <- struct{}{}:
+				defer func() { <-budget }()
+			case <-ctx.Done():
+				return
+			}
+
+			issues, err := c.fetchScroll(ctx, p.Query, func(fetched int) {
+				mu.Lock()
+				defer mu.Unlock()
+				states[i].Fetched = fetched
+				report()
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			states[i].Done = true
+			if err != nil {
+				states[i].Error = err.Error()
+				log.Printf("Error listing issues of partition %s: %v", p.Name, err)
+			} else {
+				listed[i] = issues
+			}
+			report()
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, states, err
+	}
+
+	// an issue listed twice, also under different keys after a move, is kept in its latest state
+	byID := make(map[string]Issue)
+	for _, issues := range listed {
+		for _, issue := range issues {
+			id := issue.ID
+			if id == "" {
+				id = issue.Key
+			}
+			if known, ok := byID[id]; !ok || issue.UpdatedAt.After(known.UpdatedAt.Time) {
+				byID[id] = issue
+			}
+		}
+	}
+
+	merged := make([]Issue, 0, len(byID))
+	for _, issue := range byID {
+		merged = append(merged, issue)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if !merged[i].UpdatedAt.Equal(merged[j].UpdatedAt.Time) {
+			return merged[i].UpdatedAt.After(merged[j].UpdatedAt.Time)
+		}
+		return merged[i].Key < merged[j].Key
+	})
+
+	log.Printf("Total issues fetched: %d in %d partitions", len(merged), len(partitions))
+	return merged, states, nil
+}
+
+// failedPartitions - partitions that could not be listed
+func failedPartitions(states []PartitionProgress) []PartitionProgress {
+	var failed []PartitionProgress
+	for _, s := range states {
+		if s.Error != "" {
+			failed = append(failed, s)
+		}
+	}
+	return failed
+}
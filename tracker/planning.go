@@ -0,0 +1,119 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// planning dictionary kinds
+const (
+	DictComponent = "component"
+	DictVersion   = "version"
+	DictBoard     = "board"
+	DictSprint    = "sprint"
+)
+
+// SprintInProgress - status of the current sprint of a board
+const SprintInProgress = "in_progress"
+
+// RefID - ID of a reference, returned by Tracker as a string or a number
+type RefID string
+
+// UnmarshalJSON - accepts a string or a number
+func (id *RefID) UnmarshalJSON(data []byte) error {
+	*id = RefID(strings.Trim(string(data), `"`))
+	if *id == "null" {
+		*id = ""
+	}
+	return nil
+}
+
+// PlanningRef - reference to a component, version or sprint
+type PlanningRef struct {
+	ID      RefID  `json:"id"`
+	Display string `json:"display"`
+}
+
+// IndexedRef - component, version or sprint of an issue prepared for indexing
+type IndexedRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// convertRefs - converts references keeping their order
+func convertRefs(refs []PlanningRef) []IndexedRef {
+	if len(refs) == 0 {
+		return nil
+	}
+	indexed := make([]IndexedRef, 0, len(refs))
+	for _, r := range refs {
+		indexed = append(indexed, IndexedRef{ID: string(r.ID), Name: r.Display})
+	}
+	return indexed
+}
+
+// fetchPlanning - loads components, versions of the queues, boards and their sprints
+func (c *Client) fetchPlanning(ctx context.Context, queues []string) ([]DictionaryEntry, error) {
+	var entries []DictionaryEntry
+
+	components, err := c.fetchDictionary(ctx, "/components", false)
+	if err != nil {
+		return nil, fmt.Errorf("fetch components: %w", err)
+	}
+	entries = appendPlanning(entries, DictComponent, components)
+
+	for _, queue := range queues {
+		versions, err := c.fetchDictionary(ctx, "/queues/"+queue+"/versions", false)
+		if err != nil {
+			return nil, fmt.Errorf("fetch versions of %s: %w", queue, err)
+		}
+		entries = appendPlanning(entries, DictVersion, versions)
+	}
+
+	boards, err := c.fetchDictionary(ctx, "/boards", false)
+	if err != nil {
+		return nil, fmt.Errorf("fetch boards: %w", err)
+	}
+	entries = appendPlanning(entries, DictBoard, boards)
+
+	for _, board := range boards {
+		id := strings.Trim(string(board.ID), `"`)
+		sprints, err := c.fetchDictionary(ctx, "/boards/"+id+"/sprints", false)
+		if err != nil {
+			return nil, fmt.Errorf("fetch sprints of board %s: %w", id, err)
+		}
+		entries = appendPlanning(entries, DictSprint, sprints)
+	}
+
+	return entries, nil
+}
+
+// appendPlanning - converts catalog items keyed by their IDs, in the order Tracker returns them
+func appendPlanning(entries []DictionaryEntry, kind string, items []dictionaryItem) []DictionaryEntry {
+	for i, item := range items {
+		entry := DictionaryEntry{
+			Kind:   kind,
+			Key:    strings.Trim(string(item.ID), `"`),
+			Names:  item.Name,
+			Order:  i,
+			Status: item.Status,
+			Start:  item.StartDate.Date(),
+			End:    item.EndDate.Date(),
+		}
+		entry.ID = entry.Key
+		if item.Queue != nil {
+			entry.Parent = item.Queue.Key
+		}
+		if item.Board != nil {
+			entry.Parent = string(item.Board.ID)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// boardRef - board of a sprint
+type boardRef struct {
+	ID RefID `json:"id"`
+}
@@ -0,0 +1,102 @@
+package tracker
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of summons
+const (
+	// SummonKindSummon - user summoned with the Tracker summon feature
+	SummonKindSummon = "summon"
+	// SummonKindMention - user @mentioned in a comment text
+	SummonKindMention = "mention"
+)
+
+// IndexedSummons - summons or mention of a user in a comment the user has not replied to yet
+type IndexedSummons struct {
+	User IndexedUser `json:"user"`
+	// Kind - summon or mention
+	Kind       string    `json:"kind"`
+	CommentID  int64     `json:"comment_id"`
+	Author     string    `json:"author"`
+	AuthorName string    `json:"author_name"`
+	SummonedAt time.Time `json:"summoned_at"`
+}
+
+// pendingSummons - returns users summoned or mentioned in comments who have not commented since.
+// Repeated summons of a user waiting for reply keep the earliest one
+func pendingSummons(comments []Comment) []IndexedSummons {
+	sorted := make([]Comment, len(comments))
+	copy(sorted, comments)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt.Before(sorted[j].CreatedAt.Time) })
+
+	// mentions carry only logins, known users give them IDs and names
+	known := make(map[string]UserRef)
+	remember := func(u UserRef) {
+		if u.Login != "" {
+			known[strings.ToLower(u.Login)] = u
+		}
+	}
+	for _, c := range sorted {
+		remember(c.Author)
+		for _, u := range c.Summonees {
+			remember(u)
+		}
+	}
+
+	var pending []IndexedSummons
+	for _, c := range sorted {
+		// the author has replied to everything before the comment
+		kept := pending[:0]
+		for _, s := range pending {
+			if !sameUser(s.User, c.Author) {
+				kept = append(kept, s)
+			}
+		}
+		pending = kept
+
+		summon := func(u UserRef, kind string) {
+			if u.ID == "" && u.Login == "" || sameUser(IndexedUser{ID: u.ID, Login: u.Login}, c.Author) {
+				return
+			}
+			for _, s := range pending {
+				if sameUser(s.User, u) {
+					return
+				}
+			}
+			name := u.Display
+			if name == "" {
+				name = u.Login
+			}
+			pending = append(pending, IndexedSummons{
+				User:       IndexedUser{ID: u.ID, Login: u.Login, Name: name},
+				Kind:       kind,
+				CommentID:  c.ID,
+				Author:     c.Author.ID,
+				AuthorName: c.Author.Display,
+				SummonedAt: c.CreatedAt.Time,
+			})
+		}
+		for _, u := range c.Summonees {
+			summon(u, SummonKindSummon)
+		}
+		for _, login := range ParseMarkup(c.Text).Mentions {
+			u, ok := known[strings.ToLower(login)]
+			if !ok {
+				u = UserRef{Login: login}
+			}
+			summon(u, SummonKindMention)
+		}
+	}
+	return pending
+}
+
+// sameUser - checks if the user reference points to the indexed user by ID or login
+func sameUser(user IndexedUser, ref UserRef) bool {
+	if user.ID != "" && ref.ID != "" && user.ID == ref.ID {
+		return true
+	}
+	return user.Login != "" && ref.Login != "" && strings.EqualFold(user.Login, ref.Login)
+}
@@ -2,8 +2,10 @@ package tracker
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
-	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -30,44 +32,370 @@ type IndexedIssue struct {
 	Tags         []string  `json:"tags"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+
+	// QueueName, PriorityName, TypeName, ResolutionName - display names of the keys
+	QueueName      string `json:"queue_name,omitempty"`
+	PriorityName   string `json:"priority_name,omitempty"`
+	TypeName       string `json:"type_name,omitempty"`
+	ResolutionName string `json:"resolution_name,omitempty"`
+	// StatusCategory - open, in_progress or done
+	StatusCategory string `json:"status_category,omitempty"`
+	// ResolvedAt - time of the resolution, zero if the issue is open
+	ResolvedAt time.Time `json:"resolved_at,omitempty"`
+	// Deadline, Start, End - planned dates, zero if not set
+	Deadline time.Time `json:"deadline,omitempty"`
+	Start    time.Time `json:"start,omitempty"`
+	End      time.Time `json:"end,omitempty"`
+	// Components, FixVersions, AffectedVersions, Sprints - planning references
+	Components       []IndexedRef `json:"components,omitempty"`
+	FixVersions      []IndexedRef `json:"fix_versions,omitempty"`
+	AffectedVersions []IndexedRef `json:"affected_versions,omitempty"`
+	Sprints          []IndexedRef `json:"sprints,omitempty"`
+	// Followers, Votes, CommentCount, LastCommentedAt - popularity and activity signals
+	Followers       []IndexedUser `json:"followers,omitempty"`
+	Votes           int           `json:"votes"`
+	CommentCount    int           `json:"comment_count"`
+	LastCommentedAt time.Time     `json:"last_commented_at,omitempty"`
+	// Summons - summoned or mentioned users who have not commented since
+	Summons []IndexedSummons `json:"summons,omitempty"`
+	// PreviousKeys - keys the issue had before moves between queues, and its aliases
+	PreviousKeys []string `json:"previous_keys,omitempty"`
+
+	// DescriptionRaw - description markup as returned by Tracker, for rendering
+	DescriptionRaw string           `json:"description_raw,omitempty"`
+	Comments       []IndexedComment `json:"comments,omitempty"`
+	SyncedAt       time.Time        `json:"synced_at"`
+
+	// CodeText - contents of code blocks in the description and comments
+	CodeText string `json:"code_text,omitempty"`
+	// Links, Mentions, IssueRefs - references found in the description and comments
+	Links     []string `json:"links,omitempty"`
+	Mentions  []string `json:"mentions,omitempty"`
+	IssueRefs []string `json:"issue_refs,omitempty"`
+
+	IssueLinks []IndexedLink `json:"issue_links,omitempty"`
+
+	// Parent, Epic - keys of the parent issue and the epic
+	Parent string `json:"parent,omitempty"`
+	Epic   string `json:"epic,omitempty"`
+	// Ancestors - keys of all ancestors from the hierarchy root down to the direct parent
+	Ancestors []string `json:"ancestors,omitempty"`
+
+	// Changes - field changes from the changelog; after an incremental sync only the new ones
+	Changes []IndexedChange `json:"changelog,omitempty"`
+
+	Attachments []IndexedAttachment `json:"attachments,omitempty"`
+
+	Worklog []IndexedWorklog `json:"worklog,omitempty"`
+	// WorklogText - worklog comments for full-text search
+	WorklogText string `json:"worklog_text,omitempty"`
+
+	Checklist []IndexedChecklistItem `json:"checklist,omitempty"`
+	// ChecklistText - checklist item texts for full-text search
+	ChecklistText string `json:"checklist_text,omitempty"`
+
+	// CustomFields - raw JSON of local and extra fields by field ID
+	CustomFields map[string]json.RawMessage `json:"custom_fields,omitempty"`
+
+	// Unloaded - details that failed to load, their stored rows are kept
+	Unloaded []IssuePart `json:"-"`
+}
+
+// IssuePart - issue details loaded with a separate request
+type IssuePart string
+
+// Issue details
+const (
+	PartComments    IssuePart = "comments"
+	PartLinks       IssuePart = "links"
+	PartChanges     IssuePart = "changelog"
+	PartWorklog     IssuePart = "worklog"
+	PartAttachments IssuePart = "attachments"
+)
+
+// Loaded - checks if the details were loaded, so that their stored rows may be replaced
+func (i IndexedIssue) Loaded(part IssuePart) bool {
+	return !slices.Contains(i.Unloaded, part)
+}
+
+// Complete - checks if all details were loaded
+func (i IndexedIssue) Complete() bool {
+	return len(i.Unloaded) == 0
+}
+
+// IndexedLink - link to another issue prepared for indexing
+type IndexedLink struct {
+	Relation  string `json:"relation"`
+	Type      string `json:"type"`
+	Direction string `json:"direction"`
+	Key       string `json:"key"`
+	Display   string `json:"display,omitempty"`
+}
+
+// IndexedComment - comment prepared for indexing, text keeps the original markup
+type IndexedComment struct {
+	ID         int64     `json:"id"`
+	Author     string    `json:"author"`
+	AuthorName string    `json:"author_name"`
+	Text       string    `json:"text"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// syncBatchSize - processed issues passed to SyncOptions.OnBatch at once
+const syncBatchSize = 100
+
+// Sync phases
+const (
+	// PhaseListing - issues are listed, one scroll per partition
+	PhaseListing = "listing"
+	// PhaseRecent - issues updated recently, processed first so that they are searchable soon
+	PhaseRecent = "recent"
+	// PhaseBackfill - older issues, processed afterwards with fewer workers
+	PhaseBackfill = "backfill"
+)
+
+// SyncOptions - synchronization parameters
+type SyncOptions struct {
+	// Queues - queues to sync, all accessible queues if empty
+	Queues []string
+	// Workers - number of concurrent detail loaders
+	Workers int
+	// FetchWorkers - number of concurrent scrolls listing the issues, one per queue or creation year
+	FetchWorkers int
+	// RecentDays - issues updated within this many days are processed before older ones;
+	// all issues are processed as recent if zero
+	RecentDays int
+	// BackfillWorkers - number of concurrent detail loaders for older issues, half of Workers if zero
+	BackfillWorkers int
+	// ChangelogCursor - returns the ID of the last stored changelog entry of an issue,
+	// so that only newer entries are fetched. Nil or empty ID fetches the whole changelog
+	ChangelogCursor func(ctx context.Context, issueKey string) (string, error)
+	// Attachments - attachment download parameters
+	Attachments AttachmentOptions
+	// Checkpoint - progress of an interrupted sync, optional.
+	// Issues processed by it and not updated since are skipped
+	Checkpoint SyncCheckpoint
+	// OnBatch - receives processed issues in batches instead of returning them at the end,
+	// so that they are stored as the sync goes; optional
+	OnBatch func(ctx context.Context, issues []IndexedIssue) error
+	// Progress - receives the progress after listing and after each processed batch, optional
+	Progress func(SyncProgress)
+}
+
+// SyncCheckpoint - durable storage of the sync progress
+type SyncCheckpoint interface {
+	// Processed - returns keys of the issues processed by an interrupted sync
+	// with their update time at that moment
+	Processed(ctx context.Context) (map[string]time.Time, error)
+}
+
+// SyncProgress - progress of a running sync
+type SyncProgress struct {
+	// Total - number of listed issues
+	Total int `json:"total"`
+	// Processed - issues with loaded details, including those skipped as processed before
+	Processed int `json:"processed"`
+	// Resumed - issues processed by an interrupted sync and skipped
+	Resumed int `json:"resumed"`
+	// Phase - running phase: listing, recent or backfill
+	Phase string `json:"phase"`
+	// Partitions - listing progress per queue or creation year
+	Partitions []PartitionProgress `json:"partitions,omitempty"`
+	// Recent, Backfill - progress of the phases
+	Recent   PhaseProgress `json:"recent"`
+	Backfill PhaseProgress `json:"backfill"`
+}
+
+// PhaseProgress - progress of a sync phase
+type PhaseProgress struct {
+	Total     int `json:"total"`
+	Processed int `json:"processed"`
+}
+
+// Done - checks if all issues of the phase are processed
+func (p PhaseProgress) Done() bool {
+	return p.Processed >= p.Total
 }
 
 // SyncResult - synchronization result summary
 type SyncResult struct {
 	TotalIssues   int
 	TotalComments int
-	ProcessedAt   time.Time
-	Errors        []error
+	TotalChanges  int
+	// TotalAttachments, ExtractedAttachments - listed attachments and those with extracted text
+	TotalAttachments     int
+	ExtractedAttachments int
+	TotalWorklog         int
+	// Resumed - issues processed by an interrupted sync and skipped
+	Resumed int
+	// Keys - keys of all listed issues, including the skipped ones
+	Keys []string
+	// Partitions - listing results per queue or creation year
+	Partitions  []PartitionProgress
+	ProcessedAt time.Time
+	Errors      []error
 }
 
-// InitialSync - performs the initial synchronization: fetches all issues and their comments
-func (c *Client) InitialSync(ctx context.Context, queues []string, workers int) ([]IndexedIssue, *SyncResult, error) {
+// InitialSync - performs the initial synchronization: fetches all issues with their comments,
+// links and changelog. Recently updated issues are processed first, older ones are backfilled
+// with fewer workers. With a checkpoint, issues processed by an interrupted sync are skipped
+func (c *Client) InitialSync(ctx context.Context, opts SyncOptions) ([]IndexedIssue, *SyncResult, error) {
 	result := &SyncResult{
 		ProcessedAt: time.Now(),
 	}
 
-	// 1. Load all issues
+	s := &issueSync{
+		client:      c,
+		opts:        opts,
+		result:      result,
+		attachments: newAttachmentLoader(c, opts.Attachments),
+		progress:    SyncProgress{Phase: PhaseListing},
+	}
+
+	// 1. List all issues with a scroll per partition
 	log.Println("Starting initial sync...")
-	issues, err := c.FetchAllIssues(ctx, queues)
+	partitions := issuePartitions(opts.Queues, time.Now())
+	issues, states, err := c.FetchPartitioned(ctx, partitions, opts.FetchWorkers, func(states []PartitionProgress) {
+		s.progress.Partitions = states
+		s.report()
+	})
+	result.Partitions = states
+	s.progress.Partitions = states
 	if err != nil {
 		return nil, result, err
 	}
+	// issues of the listed partitions are synced, the failed ones are retried by the next sync
+	failed := failedPartitions(states)
+	for _, p := range failed {
+		result.Errors = append(result.Errors, fmt.Errorf("list issues of partition %s: %s", p.Name, p.Error))
+	}
 	result.TotalIssues = len(issues)
-	log.Printf("Fetched %d issues, loading comments...", len(issues))
+	for _, issue := range issues {
+		result.Keys = append(result.Keys, issue.Key)
+	}
+
+	// ancestors are resolved over all listed issues, since details are processed in batches
+	s.parents = listedParents(issues)
+
+	processed, err := checkpointed(ctx, opts.Checkpoint)
+	if err != nil {
+		return nil, result, fmt.Errorf("read sync checkpoint: %w", err)
+	}
+	s.progress.Total = len(issues)
+	s.progress.Phase = PhaseRecent
+
+	// 2. Split issues into phases, leaving out the processed ones
+	since := time.Now().AddDate(0, 0, -opts.RecentDays)
+	var recent, older []Issue
+	for _, issue := range issues {
+		backfill := opts.RecentDays > 0 && issue.UpdatedAt.Before(since)
+		phase := &s.progress.Recent
+		if backfill {
+			phase = &s.progress.Backfill
+		}
+		phase.Total++
+
+		if skipProcessed(processed, issue) {
+			phase.Processed++
+			s.progress.Processed++
+			s.progress.Resumed++
+			continue
+		}
+		if backfill {
+			older = append(older, issue)
+		} else {
+			recent = append(recent, issue)
+		}
+	}
+	result.Resumed = s.progress.Resumed
+	if result.Resumed > 0 {
+		log.Printf("Resuming sync from %d/%d issues", result.Resumed, len(issues))
+	}
+	if opts.RecentDays > 0 {
+		log.Printf("Fetched %d issues, %d updated in the last %d days go first, loading comments...",
+			len(issues), s.progress.Recent.Total, opts.RecentDays)
+	} else {
+		log.Printf("Fetched %d issues, loading comments...", len(issues))
+	}
+	s.report()
 
-	// 2. Load comments for issues with concurrency
+	// 3. Load details of recent issues, then backfill older ones with fewer workers
+	workers := opts.Workers
 	if workers <= 0 {
 		workers = 5
 	}
+	if err := s.process(ctx, recent, &s.progress.Recent, workers); err != nil {
+		return nil, result, err
+	}
 
-	type issueWithComments struct {
-		issue    Issue
-		comments []Comment
-		err      error
+	backfillWorkers := opts.BackfillWorkers
+	if backfillWorkers <= 0 {
+		backfillWorkers = max(workers/2, 1)
+	}
+	s.progress.Phase = PhaseBackfill
+	s.report()
+	if len(older) > 0 {
+		log.Printf("Recent issues synced, backfilling %d older issues with %d workers...", len(older), backfillWorkers)
+	}
+	if err := s.process(ctx, older, &s.progress.Backfill, backfillWorkers); err != nil {
+		return nil, result, err
+	}
+
+	log.Printf("Initial sync completed: %d issues, %d comments, %d new changes, %d attachments (%d with text), %d worklog entries, %d errors",
+		result.TotalIssues, result.TotalComments, result.TotalChanges,
+		result.TotalAttachments, result.ExtractedAttachments, result.TotalWorklog, len(result.Errors))
+
+	// the listing is incomplete, so the sync is not finished
+	if len(failed) > 0 {
+		names := make([]string, len(failed))
+		for i, p := range failed {
+			names[i] = p.Name
+		}
+		return s.indexed, result, fmt.Errorf("%d of %d partitions could not be listed: %s",
+			len(failed), len(states), strings.Join(names, ", "))
 	}
 
+	return s.indexed, result, nil
+}
+
+// issueSync - state of a running initial sync
+type issueSync struct {
+	client      *Client
+	opts        SyncOptions
+	parents     map[string]string
+	attachments *attachmentLoader
+
+	result   *SyncResult
+	progress SyncProgress
+	// indexed - processed issues, collected if they are not passed to OnBatch
+	indexed []IndexedIssue
+}
+
+// report - passes the progress to the callback
+func (s *issueSync) report() {
+	if s.opts.Progress != nil {
+		s.opts.Progress(s.progress)
+	}
+}
+
+// process - loads details of the issues with concurrent workers and stores them in batches
+func (s *issueSync) process(ctx context.Context, issues []Issue, phase *PhaseProgress, workers int) error {
+	if len(issues) == 0 {
+		return nil
+	}
+
+	type issueWithDetails struct {
+		issue   Issue
+		details issueDetails
+		errs    []error
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	jobs := make(chan Issue, len(issues))
-	results := make(chan issueWithComments, len(issues))
+	results := make(chan issueWithDetails, len(issues))
 
 	var wg sync.WaitGroup
 	for i := 0; i < workers; i++ {
@@ -75,11 +403,11 @@ func (c *Client) InitialSync(ctx context.Context, queues []string, workers int)
 		go func() {
 			defer wg.Done()
 			for issue := range jobs {
-				comments, err := c.FetchIssueComments(ctx, issue.Key)
-				results <- issueWithComments{
-					issue:    issue,
-					comments: comments,
-					err:      err,
+				details, errs := s.client.loadDetails(ctx, issue, s.opts, s.attachments)
+				results <- issueWithDetails{
+					issue:   issue,
+					details: details,
+					errs:    errs,
 				}
 			}
 		}()
@@ -97,40 +425,170 @@ func (c *Client) InitialSync(ctx context.Context, queues []string, workers int)
 		close(results)
 	}()
 
-	// 3. Collect results and convert to IndexedIssue
-	var indexed []IndexedIssue
-	processed := 0
+	// collect results and convert to IndexedIssue
+	var batch []IndexedIssue
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := s.opts.OnBatch(ctx, batch); err != nil {
+			return err
+		}
+		batch = nil
+		s.report()
+		return nil
+	}
 
+	result := s.result
+	var batchErr error
 	for r := range results {
-		processed++
-		if processed%100 == 0 {
-			log.Printf("Processing comments: %d/%d", processed, len(issues))
+		s.progress.Processed++
+		phase.Processed++
+		if s.progress.Processed%100 == 0 {
+			log.Printf("Processing comments: %d/%d", s.progress.Processed, s.progress.Total)
 		}
 
-		if r.err != nil {
-			result.Errors = append(result.Errors, r.err)
-			log.Printf("Error fetching comments for issue %s: %v", r.issue.Key, r.err)
+		for _, err := range r.errs {
+			result.Errors = append(result.Errors, err)
+			log.Printf("Error loading details for issue %s: %v", r.issue.Key, err)
 		}
 
-		result.TotalComments += len(r.comments)
+		result.TotalComments += len(r.details.comments)
+		result.TotalChanges += len(r.details.changes)
+		result.TotalAttachments += len(r.details.attachments)
+		result.TotalWorklog += len(r.details.worklog)
+		for _, a := range r.details.attachments {
+			if a.Text != "" {
+				result.ExtractedAttachments++
+			}
+		}
+
+		issue := convertToIndexed(r.issue, r.details)
+		issue.Ancestors = ancestorPath(s.parents, issue.Key)
+
+		if s.opts.OnBatch == nil {
+			s.indexed = append(s.indexed, issue)
+			continue
+		}
+		// details loaded after cancellation may be incomplete, so they are not stored as processed;
+		// after a failed batch the remaining results are drained
+		if batchErr != nil || ctx.Err() != nil {
+			continue
+		}
+		batch = append(batch, issue)
+		if len(batch) >= syncBatchSize {
+			if batchErr = flush(); batchErr != nil {
+				// workers stop loading details that would not be stored
+				cancel()
+			}
+		}
+	}
+	if s.opts.OnBatch != nil && batchErr == nil && ctx.Err() == nil {
+		batchErr = flush()
+	}
+	if batchErr != nil {
+		return fmt.Errorf("store processed issues: %w", batchErr)
+	}
+	return ctx.Err()
+}
 
-		indexed = append(indexed, convertToIndexed(r.issue, r.comments))
+// checkpointed - reads issues processed by an interrupted sync, none without a checkpoint
+func checkpointed(ctx context.Context, checkpoint SyncCheckpoint) (map[string]time.Time, error) {
+	if checkpoint == nil {
+		return nil, nil
 	}
+	return checkpoint.Processed(ctx)
+}
 
-	log.Printf("Initial sync completed: %d issues, %d comments, %d errors",
-		result.TotalIssues, result.TotalComments, len(result.Errors))
+// skipProcessed - checks if the issue was processed by an interrupted sync and not updated since
+func skipProcessed(processed map[string]time.Time, issue Issue) bool {
+	// the checkpoint keeps seconds only
+	updated, ok := processed[issue.Key]
+	return ok && updated.Unix() == issue.UpdatedAt.Unix()
+}
 
-	return indexed, result, nil
+// issueDetails - issue data loaded with separate requests
+type issueDetails struct {
+	comments    []Comment
+	links       []IssueLink
+	changes     []ChangelogEntry
+	attachments []IndexedAttachment
+	worklog     []IndexedWorklog
+	// unloaded - parts that failed to load
+	unloaded []IssuePart
 }
 
-// convertToIndexed - converts Issue and its comments to IndexedIssue
-func convertToIndexed(issue Issue, comments []Comment) IndexedIssue {
+// loadDetails - loads comments, links, new changelog entries, worklog and attachments of the issue.
+// Failed parts are left empty, marked as unloaded and reported in the returned errors
+func (c *Client) loadDetails(ctx context.Context, issue Issue, opts SyncOptions, attachments *attachmentLoader) (issueDetails, []error) {
+	var details issueDetails
+	var errs []error
+	fail := func(part IssuePart, err error) {
+		details.unloaded = append(details.unloaded, part)
+		errs = append(errs, err)
+	}
+
+	comments, err := c.FetchIssueComments(ctx, issue.Key)
+	if err != nil {
+		fail(PartComments, err)
+	}
+	details.comments = comments
+
+	links, err := c.FetchIssueLinks(ctx, issue.Key)
+	if err != nil {
+		fail(PartLinks, err)
+	}
+	details.links = links
+
+	changes, err := c.fetchNewChanges(ctx, issue.Key, opts)
+	if err != nil {
+		fail(PartChanges, err)
+	}
+	details.changes = changes
+
+	worklog, err := c.FetchIssueWorklog(ctx, issue.Key)
+	if err != nil {
+		fail(PartWorklog, err)
+	}
+	var worklogErrs []error
+	details.worklog, worklogErrs = convertWorklog(worklog)
+	errs = append(errs, worklogErrs...)
+
+	files, err := c.FetchIssueAttachments(ctx, issue.Key)
+	if err != nil {
+		fail(PartAttachments, err)
+		return details, errs
+	}
+	indexed, attachmentErrs := attachments.load(ctx, issue.Key, files)
+	details.attachments = indexed
+	errs = append(errs, attachmentErrs...)
+
+	return details, errs
+}
+
+// fetchNewChanges - loads changelog entries following the last stored one
+func (c *Client) fetchNewChanges(ctx context.Context, issueKey string, opts SyncOptions) ([]ChangelogEntry, error) {
+	var cursor string
+	if opts.ChangelogCursor != nil {
+		var err error
+		if cursor, err = opts.ChangelogCursor(ctx, issueKey); err != nil {
+			// stored entries are kept, so skip the changelog until the next sync
+			return nil, fmt.Errorf("changelog cursor of %s: %w", issueKey, err)
+		}
+	}
+	return c.FetchIssueChangelog(ctx, issueKey, cursor)
+}
+
+// convertToIndexed - converts Issue and its details to IndexedIssue
+func convertToIndexed(issue Issue, details issueDetails) IndexedIssue {
+	description := ParseMarkup(issue.Description)
+
 	indexed := IndexedIssue{
 		ID:          issue.ID,
 		Key:         issue.Key,
 		URL:         "https://tracker.yandex.ru/" + issue.Key,
 		Summary:     issue.Summary,
-		Description: stripHTML(issue.Description),
+		Description: description.Text,
 		Queue:       issue.Queue.Key,
 		Status:      issue.Status.Key,
 		StatusName:  issue.Status.Display,
@@ -141,45 +599,116 @@ func convertToIndexed(issue Issue, comments []Comment) IndexedIssue {
 		Tags:        issue.Tags,
 		CreatedAt:   issue.CreatedAt.Time,
 		UpdatedAt:   issue.UpdatedAt.Time,
+
+		DescriptionRaw: issue.Description,
+		SyncedAt:       time.Now(),
 	}
 
+	indexed.QueueName = issue.Queue.Display
+	indexed.PriorityName = issue.Priority.Display
+	indexed.TypeName = issue.Type.Display
+
+	// refined with the statuses dictionary when indexing
+	indexed.StatusCategory = CategoryOpen
 	if issue.Resolution != nil {
 		indexed.Resolution = issue.Resolution.Key
+		indexed.ResolutionName = issue.Resolution.Display
+		indexed.StatusCategory = CategoryDone
+	}
+
+	if issue.ResolvedAt != nil {
+		indexed.ResolvedAt = issue.ResolvedAt.Time
 	}
+	indexed.Deadline = issue.Deadline.Date()
+	indexed.Start = issue.Start.Date()
+	indexed.End = issue.End.Date()
+
+	indexed.Components = convertRefs(issue.Components)
+	indexed.FixVersions = convertRefs(issue.FixVersions)
+	indexed.AffectedVersions = convertRefs(issue.AffectedVersions)
+	indexed.Sprints = convertRefs(issue.Sprints)
+
+	indexed.Followers = convertFollowers(issue.Followers)
+	indexed.Votes = issue.Votes
+	indexed.CommentCount = len(details.comments)
+	indexed.LastCommentedAt = lastCommented(details.comments)
+	indexed.Summons = pendingSummons(details.comments)
+	indexed.PreviousKeys = previousKeys(issue, details.changes)
 
 	if issue.Assignee != nil {
 		indexed.Assignee = issue.Assignee.ID
 		indexed.AssigneeName = issue.Assignee.Display
 	}
 
+	if issue.Parent != nil {
+		indexed.Parent = issue.Parent.Key
+	}
+	if issue.Epic != nil {
+		indexed.Epic = issue.Epic.Key
+	}
+
+	codeTexts := []string{description.Code}
+	refs := description
+
 	// combine comments text
 	var commentTexts []string
-	for _, c := range comments {
-		text := stripHTML(c.Text)
-		if text != "" {
-			commentTexts = append(commentTexts, text)
+	for _, c := range details.comments {
+		markup := ParseMarkup(c.Text)
+		if markup.Text != "" {
+			commentTexts = append(commentTexts, markup.Text)
 		}
+		codeTexts = append(codeTexts, markup.Code)
+		refs.Links = append(refs.Links, markup.Links...)
+		refs.Mentions = append(refs.Mentions, markup.Mentions...)
+		refs.IssueKeys = append(refs.IssueKeys, markup.IssueKeys...)
+
+		indexed.Comments = append(indexed.Comments, IndexedComment{
+			ID:         c.ID,
+			Author:     c.Author.ID,
+			AuthorName: c.Author.Display,
+			Text:       c.Text,
+			CreatedAt:  c.CreatedAt.Time,
+			UpdatedAt:  c.UpdatedAt.Time,
+		})
 	}
 	indexed.CommentsText = strings.Join(commentTexts, "\n\n")
+	indexed.CodeText = strings.TrimSpace(strings.Join(codeTexts, "\n\n"))
 
-	return indexed
-}
+	indexed.Links = uniqueStrings(refs.Links)
+	indexed.Mentions = uniqueStrings(refs.Mentions)
+	// an issue is not a reference to itself
+	for _, key := range uniqueStrings(refs.IssueKeys) {
+		if key != issue.Key {
+			indexed.IssueRefs = append(indexed.IssueRefs, key)
+		}
+	}
+
+	for _, l := range details.links {
+		indexed.IssueLinks = append(indexed.IssueLinks, IndexedLink{
+			Relation:  LinkRelation(l.Type.ID, l.Direction),
+			Type:      l.Type.ID,
+			Direction: l.Direction,
+			Key:       l.Object.Key,
+			Display:   l.Object.Display,
+		})
+	}
 
-// stripHTML - removes HTML tags from a string
-func stripHTML(s string) string {
-	// TODO: check for library for more robust HTML stripping
-	re := regexp.MustCompile(`<[^>]*>`)
-	s = re.ReplaceAllString(s, "")
+	indexed.Changes = convertChangelog(details.changes)
+	indexed.Attachments = details.attachments
 
-	// decode common HTML entities
-	s = strings.ReplaceAll(s, "&nbsp;", " ")
-	s = strings.ReplaceAll(s, "&amp;", "&")
-	s = strings.ReplaceAll(s, "&lt;", "<")
-	s = strings.ReplaceAll(s, "&gt;", ">")
-	s = strings.ReplaceAll(s, "&quot;", "\"")
+	indexed.Worklog = details.worklog
+	var worklogTexts []string
+	for _, w := range details.worklog {
+		if w.Comment != "" {
+			worklogTexts = append(worklogTexts, ParseMarkup(w.Comment).Text)
+		}
+	}
+	indexed.WorklogText = strings.Join(worklogTexts, "\n\n")
 
-	// remove extra spaces
-	s = strings.TrimSpace(s)
+	indexed.Checklist, indexed.ChecklistText = convertChecklist(issue.Checklist)
 
-	return s
+	indexed.CustomFields = issue.Extra
+	indexed.Unloaded = details.unloaded
+
+	return indexed
 }
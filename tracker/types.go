@@ -1,6 +1,8 @@
 package tracker
 
 import (
+	"encoding/json"
+	"reflect"
 	"strings"
 	"time"
 )
@@ -40,24 +42,113 @@ func (t *TrackerTime) UnmarshalJSON(data []byte) error {
 	return parseErr
 }
 
+// TrackerDate - calendar date without time, e.g. a deadline, in the local time zone
+type TrackerDate struct {
+	time.Time
+}
+
+// UnmarshalJSON - parses a date as YYYY-MM-DD, falling back to timestamp formats
+func (d *TrackerDate) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+
+	if parsed, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		d.Time = parsed
+		return nil
+	}
+
+	var t TrackerTime
+	if err := t.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	d.Time = t.Time
+	return nil
+}
+
+// Date - returns the date, zero if it is not set
+func (d *TrackerDate) Date() time.Time {
+	if d == nil {
+		return time.Time{}
+	}
+	return d.Time
+}
+
 // Issue - task(issue) in Yandex Tracker
 type Issue struct {
-	ID          string         `json:"id"`
-	Key         string         `json:"key"`
-	Summary     string         `json:"summary"`
-	Description string         `json:"description"`
-	Queue       QueueRef       `json:"queue"`
-	Status      StatusRef      `json:"status"`
-	Priority    PriorityRef    `json:"priority"`
-	Type        TypeRef        `json:"type"`
-	Resolution  *ResolutionRef `json:"resolution"`
-	Author      UserRef        `json:"createdBy"`
-	Assignee    *UserRef       `json:"assignee"`
-	Followers   []UserRef      `json:"followers"`
-	Tags        []string       `json:"tags"`
-	CreatedAt   TrackerTime    `json:"createdAt"`
-	UpdatedAt   TrackerTime    `json:"updatedAt"`
-	ResolvedAt  *TrackerTime   `json:"resolvedAt"`
+	ID          string          `json:"id"`
+	Key         string          `json:"key"`
+	Aliases     []string        `json:"aliases"`
+	Summary     string          `json:"summary"`
+	Description string          `json:"description"`
+	Queue       QueueRef        `json:"queue"`
+	Status      StatusRef       `json:"status"`
+	Priority    PriorityRef     `json:"priority"`
+	Type        TypeRef         `json:"type"`
+	Resolution  *ResolutionRef  `json:"resolution"`
+	Author      UserRef         `json:"createdBy"`
+	Assignee    *UserRef        `json:"assignee"`
+	Followers   []UserRef       `json:"followers"`
+	Parent      *IssueRef       `json:"parent"`
+	Epic        *IssueRef       `json:"epic"`
+	Tags        []string        `json:"tags"`
+	Checklist   []ChecklistItem `json:"checklistItems"`
+	CreatedAt   TrackerTime     `json:"createdAt"`
+	UpdatedAt   TrackerTime     `json:"updatedAt"`
+	ResolvedAt  *TrackerTime    `json:"resolvedAt"`
+	Deadline    *TrackerDate    `json:"deadline"`
+	Start       *TrackerDate    `json:"start"`
+	End         *TrackerDate    `json:"end"`
+
+	Components       []PlanningRef `json:"components"`
+	FixVersions      []PlanningRef `json:"fixVersions"`
+	AffectedVersions []PlanningRef `json:"affectedVersions"`
+	Sprints          []PlanningRef `json:"sprint"`
+
+	Votes int `json:"votes"`
+
+	// Extra - raw JSON of fields not decoded above: local queue fields and global extra fields
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// serviceFields - issue fields that describe the API object rather than the issue
+var serviceFields = []string{"self", "version"}
+
+// issueFields - JSON names of the fields decoded into Issue
+var issueFields = func() []string {
+	t := reflect.TypeOf(Issue{})
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}()
+
+// UnmarshalJSON - decodes known fields and keeps the others in Extra
+func (i *Issue) UnmarshalJSON(data []byte) error {
+	type plain Issue
+	if err := json.Unmarshal(data, (*plain)(i)); err != nil {
+		return err
+	}
+
+	var extra map[string]json.RawMessage
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return err
+	}
+	for _, name := range issueFields {
+		delete(extra, name)
+	}
+	for _, name := range serviceFields {
+		delete(extra, name)
+	}
+	if len(extra) > 0 {
+		i.Extra = extra
+	}
+	return nil
 }
 
 // Comment - comment on an issue
@@ -67,6 +158,8 @@ type Comment struct {
 	Author    UserRef     `json:"createdBy"`
 	CreatedAt TrackerTime `json:"createdAt"`
 	UpdatedAt TrackerTime `json:"updatedAt"`
+	// Summonees - users summoned in the comment
+	Summonees []UserRef `json:"summonees"`
 }
 
 // UserRef - user reference
@@ -117,3 +210,76 @@ type SearchRequest struct {
 	Filter map[string]string `json:"filter,omitempty"`
 	Order  string            `json:"order,omitempty"`
 }
+
+// IssueRef - issue reference
+type IssueRef struct {
+	ID      string `json:"id"`
+	Key     string `json:"key"`
+	Display string `json:"display"`
+}
+
+// LinkTypeRef - link type reference with inward and outward names
+type LinkTypeRef struct {
+	ID      string `json:"id"`
+	Inward  string `json:"inward"`
+	Outward string `json:"outward"`
+}
+
+// IssueLink - link of an issue to another issue.
+// Direction "outward" means the issue is the subject of the outward name
+// (e.g. "depends on" the linked issue), "inward" - of the inward one ("blocks")
+type IssueLink struct {
+	ID        int64       `json:"id"`
+	Type      LinkTypeRef `json:"type"`
+	Direction string      `json:"direction"`
+	Object    IssueRef    `json:"object"`
+}
+
+// ChangelogEntry - change of an issue: a transition, field update, comment etc.
+type ChangelogEntry struct {
+	ID        string        `json:"id"`
+	Type      string        `json:"type"`
+	UpdatedBy UserRef       `json:"updatedBy"`
+	UpdatedAt TrackerTime   `json:"updatedAt"`
+	Fields    []FieldChange `json:"fields"`
+}
+
+// FieldChange - change of a single field; values are strings, numbers, references or arrays
+type FieldChange struct {
+	Field FieldRef        `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// FieldRef - issue field reference
+type FieldRef struct {
+	ID      string `json:"id"`
+	Display string `json:"display"`
+}
+
+// Attachment - file attached to an issue
+type Attachment struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Content   string      `json:"content"`
+	Mimetype  string      `json:"mimetype"`
+	Size      int64       `json:"size"`
+	CreatedBy UserRef     `json:"createdBy"`
+	CreatedAt TrackerTime `json:"createdAt"`
+}
+
+// ChecklistItem - checklist item, returned with the issue
+type ChecklistItem struct {
+	ID       string             `json:"id"`
+	Text     string             `json:"text"`
+	Checked  bool               `json:"checked"`
+	Assignee *UserRef           `json:"assignee"`
+	Deadline *ChecklistDeadline `json:"deadline"`
+}
+
+// ChecklistDeadline - deadline of a checklist item
+type ChecklistDeadline struct {
+	Date TrackerTime `json:"date"`
+	// DeadlineType - "date" for a whole day or "dateTime" for an exact time
+	DeadlineType string `json:"deadlineType"`
+}
@@ -0,0 +1,101 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Tracker counts days and weeks of logged time in working hours
+const (
+	workDay  = 8 * time.Hour
+	workWeek = 5 * workDay
+)
+
+// Worklog - time logged on an issue
+type Worklog struct {
+	ID        int64       `json:"id"`
+	Comment   string      `json:"comment"`
+	CreatedBy UserRef     `json:"createdBy"`
+	CreatedAt TrackerTime `json:"createdAt"`
+	UpdatedAt TrackerTime `json:"updatedAt"`
+	Start     TrackerTime `json:"start"`
+	Duration  string      `json:"duration"`
+}
+
+// IndexedWorklog - worklog record prepared for indexing
+type IndexedWorklog struct {
+	ID         int64     `json:"id"`
+	Author     string    `json:"author"`
+	AuthorName string    `json:"author_name"`
+	Comment    string    `json:"comment,omitempty"`
+	Start      time.Time `json:"start"`
+	Seconds    int64     `json:"seconds"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FetchIssueWorklog - loads time logged on the specified issue
+func (c *Client) FetchIssueWorklog(ctx context.Context, issueKey string) ([]Worklog, error) {
+	respBody, _, err := c.doRequest(ctx, "GET", fmt.Sprintf("/issues/%s/worklog", issueKey), nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch worklog for %s: %w", issueKey, err)
+	}
+
+	var worklog []Worklog
+	if err := json.Unmarshal(respBody, &worklog); err != nil {
+		return nil, fmt.Errorf("unmarshal worklog: %w", err)
+	}
+
+	return worklog, nil
+}
+
+// durationRe - ISO 8601 duration as returned by Tracker, e.g. P1W2DT3H30M
+var durationRe = regexp.MustCompile(`^P(?:([\d.]+)W)?(?:([\d.]+)D)?(?:T(?:([\d.]+)H)?(?:([\d.]+)M)?(?:([\d.]+)S)?)?$`)
+
+// ParseDuration - parses an ISO 8601 duration of logged time, counting days and weeks in working hours
+func ParseDuration(s string) (time.Duration, error) {
+	m := durationRe.FindStringSubmatch(s)
+	if m == nil || s == "P" || s == "PT" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	units := []time.Duration{workWeek, workDay, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(m[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		d += time.Duration(n * float64(unit))
+	}
+	return d, nil
+}
+
+// convertWorklog - converts worklog records, skipping those with unparsable durations
+func convertWorklog(worklog []Worklog) ([]IndexedWorklog, []error) {
+	var errs []error
+	indexed := make([]IndexedWorklog, 0, len(worklog))
+	for _, w := range worklog {
+		d, err := ParseDuration(w.Duration)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("worklog %d: %w", w.ID, err))
+			continue
+		}
+		indexed = append(indexed, IndexedWorklog{
+			ID:         w.ID,
+			Author:     w.CreatedBy.ID,
+			AuthorName: w.CreatedBy.Display,
+			Comment:    w.Comment,
+			Start:      w.Start.Time,
+			Seconds:    int64(d / time.Second),
+			CreatedAt:  w.CreatedAt.Time,
+		})
+	}
+	return indexed, errs
+}